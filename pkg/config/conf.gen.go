@@ -6,6 +6,22 @@ import "reflect"
 type Expensify struct {
 	PartnerUserId string `mapstructure:"partner-user-id"`
 	PartnerUserSecret string `mapstructure:"partner-user-secret"`
+	MaxRetries int `mapstructure:"max-retries"`
+	BaseUrl string `mapstructure:"base-url"`
+	ProxyUrl string `mapstructure:"proxy-url"`
+	CaBundlePath string `mapstructure:"ca-bundle-path"`
+	RequestTimeout int `mapstructure:"request-timeout"`
+	EmployeeBatchSize int `mapstructure:"employee-batch-size"`
+	EmployeeConcurrency int `mapstructure:"employee-concurrency"`
+	PolicyPageSize int `mapstructure:"policy-page-size"`
+	IncludeNonAdminPolicies bool `mapstructure:"include-non-admin-policies"`
+	IncludePolicyIds []string `mapstructure:"include-policy-ids"`
+	ExcludePolicyIds []string `mapstructure:"exclude-policy-ids"`
+	IncludePolicyNames []string `mapstructure:"include-policy-names"`
+	ExcludePolicyNames []string `mapstructure:"exclude-policy-names"`
+	IncludePolicyTypes []string `mapstructure:"include-policy-types"`
+	ExcludePolicyTypes []string `mapstructure:"exclude-policy-types"`
+	SnapshotPath string `mapstructure:"snapshot-path"`
 }
 
 func (c* Expensify) findFieldByTag(tagValue string) (any, bool) {
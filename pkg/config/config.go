@@ -20,6 +20,107 @@ var (
 		field.WithRequired(true),
 		field.WithIsSecret(true),
 	)
+
+	maxRetriesField = field.IntField(
+		"max-retries",
+		field.WithDisplayName("Max retries"),
+		field.WithDescription("How many times a request throttled by Expensify is retried before the sync fails."),
+		field.WithDefaultValue(5),
+	)
+
+	baseURLField = field.StringField(
+		"base-url",
+		field.WithDisplayName("Base URL"),
+		field.WithDescription("Override the Expensify Integration Server endpoint, e.g. to point at a local stand-in server."),
+	)
+
+	proxyURLField = field.StringField(
+		"proxy-url",
+		field.WithDisplayName("Proxy URL"),
+		field.WithDescription("The HTTP(S) proxy used to reach the Expensify API. Defaults to the proxy from the environment."),
+	)
+
+	caBundlePathField = field.StringField(
+		"ca-bundle-path",
+		field.WithDisplayName("CA bundle path"),
+		field.WithDescription("Path to a PEM file of additional CA certificates to trust, e.g. for a TLS inspecting proxy."),
+	)
+
+	requestTimeoutField = field.IntField(
+		"request-timeout",
+		field.WithDisplayName("Request timeout"),
+		field.WithDescription("Timeout in seconds for a single request to the Expensify API."),
+		field.WithDefaultValue(300),
+	)
+
+	employeeBatchSizeField = field.IntField(
+		"employee-batch-size",
+		field.WithDisplayName("Employee batch size"),
+		field.WithDescription("How many policies to fetch employees for in a single request."),
+		field.WithDefaultValue(50),
+	)
+
+	employeeConcurrencyField = field.IntField(
+		"employee-concurrency",
+		field.WithDisplayName("Employee concurrency"),
+		field.WithDescription("How many employee requests run in parallel. Throttled requests back off together."),
+		field.WithDefaultValue(4),
+	)
+
+	policyPageSizeField = field.IntField(
+		"policy-page-size",
+		field.WithDisplayName("Policy page size"),
+		field.WithDescription("How many policies each page of policies and users walks through. A sync resumes from the last finished page, but a resumed page of users reads the employees of every earlier policy again."),
+		field.WithDefaultValue(100),
+	)
+
+	includeNonAdminPoliciesField = field.BoolField(
+		"include-non-admin-policies",
+		field.WithDisplayName("Include non-admin policies"),
+		field.WithDescription("Also sync the policies the partner user is only an auditor or employee of. They are synced read-only."),
+	)
+
+	includePolicyIDsField = field.StringSliceField(
+		"include-policy-ids",
+		field.WithDisplayName("Include policy IDs"),
+		field.WithDescription("Only sync the policies with these IDs."),
+	)
+
+	excludePolicyIDsField = field.StringSliceField(
+		"exclude-policy-ids",
+		field.WithDisplayName("Exclude policy IDs"),
+		field.WithDescription("Skip the policies with these IDs."),
+	)
+
+	includePolicyNamesField = field.StringSliceField(
+		"include-policy-names",
+		field.WithDisplayName("Include policy names"),
+		field.WithDescription("Only sync the policies whose name matches one of these regular expressions."),
+	)
+
+	excludePolicyNamesField = field.StringSliceField(
+		"exclude-policy-names",
+		field.WithDisplayName("Exclude policy names"),
+		field.WithDescription("Skip the policies whose name matches one of these regular expressions."),
+	)
+
+	includePolicyTypesField = field.StringSliceField(
+		"include-policy-types",
+		field.WithDisplayName("Include policy types"),
+		field.WithDescription("Only sync policies of these types: corporate, team or personal."),
+	)
+
+	excludePolicyTypesField = field.StringSliceField(
+		"exclude-policy-types",
+		field.WithDisplayName("Exclude policy types"),
+		field.WithDescription("Skip policies of these types: corporate, team or personal."),
+	)
+
+	snapshotPathField = field.StringField(
+		"snapshot-path",
+		field.WithDisplayName("Snapshot path"),
+		field.WithDescription("Path to the file holding the policy membership snapshots the event feed diffs. Defaults to a file in the user cache directory."),
+	)
 )
 
 //go:generate go run ./gen
@@ -27,6 +128,22 @@ var Config = field.NewConfiguration(
 	[]field.SchemaField{
 		partnerUserIdField,
 		partnerUserSecretField,
+		maxRetriesField,
+		baseURLField,
+		proxyURLField,
+		caBundlePathField,
+		requestTimeoutField,
+		employeeBatchSizeField,
+		employeeConcurrencyField,
+		policyPageSizeField,
+		includeNonAdminPoliciesField,
+		includePolicyIDsField,
+		excludePolicyIDsField,
+		includePolicyNamesField,
+		excludePolicyNamesField,
+		includePolicyTypesField,
+		excludePolicyTypesField,
+		snapshotPathField,
 	},
 	field.WithConnectorDisplayName("Expensify"),
 	field.WithHelpUrl("/docs/baton/expensify"),
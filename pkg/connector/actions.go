@@ -0,0 +1,366 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	actionSetApprover             = "set_approver"
+	actionTransferPolicyOwnership = "transfer_policy_ownership"
+	actionSetApprovalLimit        = "set_approval_limit"
+	actionTerminateEmployee       = "terminate_employee"
+)
+
+func stringArgument(name, displayName, description string, required bool) *config.Field {
+	return &config.Field{
+		Name:        name,
+		DisplayName: displayName,
+		Description: description,
+		IsRequired:  required,
+		Field:       &config.Field_StringField{StringField: &config.StringField{}},
+	}
+}
+
+var (
+	policyIDArgument = stringArgument("policy_id", "Policy ID", "The ID of the Expensify policy.", true)
+	emailArgument    = stringArgument("email", "Employee email", "The email of the employee.", true)
+	successReturn    = &config.Field{
+		Name:        "success",
+		DisplayName: "Success",
+		Field:       &config.Field_BoolField{BoolField: &config.BoolField{}},
+	}
+)
+
+var setApproverSchema = &v2.BatonActionSchema{
+	Name:        actionSetApprover,
+	DisplayName: "Set approver",
+	Description: "Change who an employee submits their expense reports to.",
+	Arguments: []*config.Field{
+		policyIDArgument,
+		emailArgument,
+		stringArgument("approver_email", "Approver email", "The email of the new approver.", true),
+	},
+	ReturnTypes: []*config.Field{
+		successReturn,
+		stringArgument("previous_approver_email", "Previous approver email", "Who the employee submitted reports to before.", false),
+	},
+}
+
+var transferPolicyOwnershipSchema = &v2.BatonActionSchema{
+	Name:        actionTransferPolicyOwnership,
+	DisplayName: "Transfer policy ownership",
+	Description: "Make an admin of the policy its owner, moving billing authority to them.",
+	Arguments: []*config.Field{
+		policyIDArgument,
+		stringArgument("new_owner_email", "New owner email", "The email of the new owner, who must be an admin of the policy.", true),
+	},
+	ReturnTypes: []*config.Field{
+		successReturn,
+		stringArgument("previous_owner_email", "Previous owner email", "Who owned the policy before.", false),
+	},
+}
+
+var setApprovalLimitSchema = &v2.BatonActionSchema{
+	Name:        actionSetApprovalLimit,
+	DisplayName: "Set approval limit",
+	Description: "Set how much an approver can approve, and who approves the reports above that limit.",
+	Arguments: []*config.Field{
+		policyIDArgument,
+		emailArgument,
+		{
+			Name:        "approval_limit",
+			DisplayName: "Approval limit",
+			Description: "The approval limit, in cents of the policy output currency.",
+			IsRequired:  true,
+			Field:       &config.Field_IntField{IntField: &config.IntField{}},
+		},
+		stringArgument("over_limit_approver_email", "Over-limit approver email", "Who approves reports above the limit. Defaults to the current over-limit approver.", false),
+	},
+	ReturnTypes: []*config.Field{
+		successReturn,
+		{
+			Name:        "previous_approval_limit",
+			DisplayName: "Previous approval limit",
+			Field:       &config.Field_IntField{IntField: &config.IntField{}},
+		},
+	},
+}
+
+var terminateEmployeeSchema = &v2.BatonActionSchema{
+	Name:        actionTerminateEmployee,
+	DisplayName: "Terminate employee",
+	Description: "Remove an employee from the given policies, or from every policy the connector administers.",
+	Arguments: []*config.Field{
+		emailArgument,
+		{
+			Name:        "policy_ids",
+			DisplayName: "Policy IDs",
+			Description: "The policies to remove the employee from. Defaults to every policy the connector administers.",
+			Field:       &config.Field_StringSliceField{StringSliceField: &config.StringSliceField{}},
+		},
+	},
+	ReturnTypes: []*config.Field{
+		successReturn,
+	},
+}
+
+// RegisterActionManager registers the custom actions for common Expensify admin operations.
+func (as *Expensify) RegisterActionManager(ctx context.Context) (connectorbuilder.CustomActionManager, error) {
+	actionManager := actions.NewActionManager(ctx)
+
+	handlers := []struct {
+		schema  *v2.BatonActionSchema
+		handler actions.ActionHandler
+	}{
+		{setApproverSchema, as.setApprover},
+		{transferPolicyOwnershipSchema, as.transferPolicyOwnership},
+		{setApprovalLimitSchema, as.setApprovalLimit},
+		{terminateEmployeeSchema, as.terminateEmployee},
+	}
+	for _, h := range handlers {
+		if err := actionManager.RegisterAction(ctx, h.schema.Name, h.schema, h.handler); err != nil {
+			return nil, fmt.Errorf("expensify-connector: failed to register action %s: %w", h.schema.Name, err)
+		}
+	}
+
+	return actionManager, nil
+}
+
+// requiredArgument returns the trimmed string argument, failing if it is missing.
+func requiredArgument(args map[string]interface{}, name string) (string, error) {
+	value := profileString(args, name)
+	if value == "" {
+		return "", status.Errorf(codes.InvalidArgument, "expensify-connector: %s is required", name)
+	}
+	return value, nil
+}
+
+// intArgument returns the non-negative integer argument, which may arrive as a number or a numeric string.
+func intArgument(args map[string]interface{}, name string) (int64, error) {
+	switch v := args[name].(type) {
+	case float64:
+		if v >= 0 && v == math.Trunc(v) && v <= math.MaxInt64 {
+			return int64(v), nil
+		}
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err == nil && n >= 0 {
+			return n, nil
+		}
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "expensify-connector: %s must be a non-negative whole number", name)
+}
+
+// requireWritable fails if the policy is read-only, so no update job is sent to a policy the partner
+// user doesn't administer.
+func (as *Expensify) requireWritable(ctx context.Context, policyID string) error {
+	policy, err := as.employees.policy(ctx, policyID)
+	if err != nil {
+		return fmt.Errorf("expensify-connector: failed to get policy: %w", err)
+	}
+	return writablePolicy(policy)
+}
+
+// fetchPolicyEmployee returns the employee record of the email in the policy, failing if they aren't a member.
+func (as *Expensify) fetchPolicyEmployee(ctx context.Context, policyID, email string) (expensify.User, annotations.Annotations, error) {
+	employees, rlData, err := as.client.GetPolicyEmployees(ctx, policyID)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return expensify.User{}, annos, fmt.Errorf("expensify-connector: failed to get policy employees: %w", err)
+	}
+
+	employee, ok := findEmployee(employees, email)
+	if !ok {
+		return expensify.User{}, annos, status.Errorf(codes.NotFound, "expensify-connector: %s is not an employee of policy %s", email, policyID)
+	}
+	return employee, annos, nil
+}
+
+func (as *Expensify) setApprover(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	argMap := args.AsMap()
+	policyID, err := requiredArgument(argMap, "policy_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	email, err := requiredArgument(argMap, "email")
+	if err != nil {
+		return nil, nil, err
+	}
+	approverEmail, err := requiredArgument(argMap, "approver_email")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := as.requireWritable(ctx, policyID); err != nil {
+		return nil, nil, err
+	}
+
+	employee, annos, err := as.fetchPolicyEmployee(ctx, policyID, email)
+	if err != nil {
+		return nil, annos, err
+	}
+	if _, _, err := as.fetchPolicyEmployee(ctx, policyID, approverEmail); err != nil {
+		return nil, annos, err
+	}
+
+	rlData, err := as.client.SetEmployeeApprover(ctx, policyID, employee.Email, approverEmail)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to set approver: %w", err)
+	}
+
+	rv, err := structpb.NewStruct(map[string]interface{}{
+		"success":                 true,
+		"policy_id":               policyID,
+		"email":                   employee.Email,
+		"approver_email":          approverEmail,
+		"previous_approver_email": employee.SubmitsTo,
+	})
+	if err != nil {
+		return nil, annos, err
+	}
+	return rv, annos, nil
+}
+
+func (as *Expensify) transferPolicyOwnership(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	argMap := args.AsMap()
+	policyID, err := requiredArgument(argMap, "policy_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	newOwner, err := requiredArgument(argMap, "new_owner_email")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy, rlData, err := as.client.GetPolicy(ctx, policyID)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to get policy: %w", err)
+	}
+	if err := writablePolicy(*policy); err != nil {
+		return nil, annos, err
+	}
+	if strings.EqualFold(policy.Owner, newOwner) {
+		return nil, annos, status.Errorf(codes.FailedPrecondition, "expensify-connector: %s already owns policy %s", newOwner, policyID)
+	}
+
+	employee, _, err := as.fetchPolicyEmployee(ctx, policyID, newOwner)
+	if err != nil {
+		return nil, annos, err
+	}
+	if employee.Role != "admin" {
+		return nil, annos, status.Errorf(codes.FailedPrecondition, "expensify-connector: %s must be an admin of policy %s to become its owner", newOwner, policyID)
+	}
+
+	rlData, err = as.client.TransferPolicyOwnership(ctx, policyID, employee.Email)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to transfer policy ownership: %w", err)
+	}
+
+	rv, err := structpb.NewStruct(map[string]interface{}{
+		"success":              true,
+		"policy_id":            policyID,
+		"owner_email":          employee.Email,
+		"previous_owner_email": policy.Owner,
+	})
+	if err != nil {
+		return nil, annos, err
+	}
+	return rv, annos, nil
+}
+
+func (as *Expensify) setApprovalLimit(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	argMap := args.AsMap()
+	policyID, err := requiredArgument(argMap, "policy_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	email, err := requiredArgument(argMap, "email")
+	if err != nil {
+		return nil, nil, err
+	}
+	limit, err := intArgument(argMap, "approval_limit")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := as.requireWritable(ctx, policyID); err != nil {
+		return nil, nil, err
+	}
+
+	employee, annos, err := as.fetchPolicyEmployee(ctx, policyID, email)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	overLimitApprover := profileString(argMap, "over_limit_approver_email")
+	if overLimitApprover == "" {
+		overLimitApprover = employee.OverLimitForwardsTo
+	}
+	if overLimitApprover == "" {
+		return nil, annos, status.Errorf(codes.InvalidArgument, "expensify-connector: %s has no over-limit approver, over_limit_approver_email is required", email)
+	}
+
+	rlData, err := as.client.SetEmployeeApprovalLimit(ctx, policyID, employee.Email, limit, overLimitApprover)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to set approval limit: %w", err)
+	}
+
+	rv, err := structpb.NewStruct(map[string]interface{}{
+		"success":                   true,
+		"policy_id":                 policyID,
+		"email":                     employee.Email,
+		"approval_limit":            limit,
+		"previous_approval_limit":   employee.ApprovalLimit,
+		"over_limit_approver_email": overLimitApprover,
+	})
+	if err != nil {
+		return nil, annos, err
+	}
+	return rv, annos, nil
+}
+
+func (as *Expensify) terminateEmployee(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	argMap := args.AsMap()
+	email, err := requiredArgument(argMap, "email")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, rlData, err := terminateEmployee(ctx, as.employees, email, profileStringList(argMap, "policy_ids"))
+	annos := annotationsWithRateLimit(rlData)
+	if results == nil && err != nil {
+		return nil, annos, err
+	}
+
+	rv, structErr := structpb.NewStruct(map[string]interface{}{
+		"success":         err == nil,
+		"email":           email,
+		"policy_removals": results,
+	})
+	if structErr != nil {
+		return nil, annos, structErr
+	}
+	return rv, annos, err
+}
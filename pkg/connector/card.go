@@ -0,0 +1,126 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const cardAssignedEntitlement = "assigned"
+
+type cardResourceType struct {
+	resourceType *v2.ResourceType
+	employees    *employeeCache
+}
+
+func (o *cardResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func cardBuilder(employees *employeeCache) *cardResourceType {
+	return &cardResourceType{
+		resourceType: resourceTypeCard,
+		employees:    employees,
+	}
+}
+
+// Create a new connector resource for an Expensify company card.
+func cardResource(domain string, card expensify.Card, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"domain":    domain,
+		"last_four": card.LastFour(),
+		"feed":      card.Bank,
+	}
+	setProfileValue(profile, "cardholder", card.Email)
+	setProfileValue(profile, "last_import", card.LastImport)
+	if card.Limit != 0 {
+		profile["limit"] = card.Limit
+	}
+
+	name := card.CardName
+	if name == "" {
+		name = fmt.Sprintf("%s card ending %s", card.Bank, card.LastFour())
+	}
+
+	// There is no card trait, so the app trait carries the card profile.
+	return rs.NewResource(
+		name,
+		resourceTypeCard,
+		strconv.FormatInt(card.CardID, 10),
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(fmt.Sprintf("%s card ending %s", card.Bank, card.LastFour())),
+		rs.WithAppTrait(rs.WithAppProfile(profile)),
+	)
+}
+
+func (o *cardResourceType) List(ctx context.Context, parentId *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	cards, rlData, err := o.employees.domainCards(ctx, parentId.Resource)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domain cards: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, card := range cards {
+		cr, err := cardResource(parentId.Resource, card, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, cr)
+	}
+
+	return rv, "", annos, nil
+}
+
+func (o *cardResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			cardAssignedEntitlement,
+			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithDisplayName(fmt.Sprintf("%s Cardholder", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Holder of the %s company card", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+func (o *cardResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil {
+		return nil, "", nil, fmt.Errorf("expensify-connector: card %s has no domain", resource.Id.Resource)
+	}
+
+	o.employees.grantsStarted()
+	cards, rlData, err := o.employees.domainCards(ctx, resource.ParentResourceId.Resource)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domain cards: %w", err)
+	}
+
+	var rv []*v2.Grant
+	for _, card := range cards {
+		if strconv.FormatInt(card.CardID, 10) != resource.Id.Resource || card.Email == "" {
+			continue
+		}
+
+		userID, err := rs.NewResourceID(resourceTypeUser, userResourceID(card.Email))
+		if err != nil {
+			return nil, "", annos, err
+		}
+		rv = append(rv, grant.NewGrant(resource, cardAssignedEntitlement, userID))
+	}
+
+	return rv, "", annos, nil
+}
@@ -2,8 +2,11 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	cfg "github.com/conductorone/baton-expensify/pkg/config"
 	"github.com/conductorone/baton-expensify/pkg/expensify"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -17,50 +20,156 @@ var (
 		Traits: []v2.ResourceType_Trait{
 			v2.ResourceType_TRAIT_USER,
 		},
-		Annotations: annotationsForUserResourceType(),
 	}
 	resourceTypePolicy = &v2.ResourceType{
 		Id:          "policy",
 		DisplayName: "Policy",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
+	resourceTypeDomain = &v2.ResourceType{
+		Id:          "domain",
+		DisplayName: "Domain",
+	}
+	resourceTypeDomainGroup = &v2.ResourceType{
+		Id:          "domain_group",
+		DisplayName: "Domain Group",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
+	resourceTypeCard = &v2.ResourceType{
+		Id:          "card",
+		DisplayName: "Company Card",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
 	}
 )
 
+var accountCreationSchema = &v2.ConnectorAccountCreationSchema{
+	FieldMap: map[string]*v2.ConnectorAccountCreationSchema_Field{
+		"email": {
+			DisplayName: "Email",
+			Required:    true,
+			Description: "The email address to invite.",
+			Placeholder: "employee@example.com",
+			Order:       1,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+		},
+		"policy_ids": {
+			DisplayName: "Policy IDs",
+			Required:    true,
+			Description: "The IDs of the policies to invite the employee into.",
+			Order:       2,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringListField{
+				StringListField: &v2.ConnectorAccountCreationSchema_StringListField{},
+			},
+		},
+		"first_name": {
+			DisplayName: "First name",
+			Order:       3,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+		},
+		"last_name": {
+			DisplayName: "Last name",
+			Order:       4,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+		},
+		"employee_id": {
+			DisplayName: "Employee ID",
+			Order:       5,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+		},
+		"approver_email": {
+			DisplayName: "Approver email",
+			Description: "Who the employee submits reports to. Defaults to the policy owner.",
+			Order:       6,
+			Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+				StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+			},
+		},
+	},
+}
+
 type Expensify struct {
-	client *expensify.Client
+	client      *expensify.Client
+	employees   *employeeCache
+	memberships *membershipFeed
+	pageSize    int
 }
 
 func (as *Expensify) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		userBuilder(as.client),
-		policyBuilder(as.client),
+		userBuilder(as.client, as.employees, as.pageSize),
+		policyBuilder(as.client, as.employees, as.pageSize),
+		domainBuilder(as.employees),
+		domainGroupBuilder(as.client),
+		cardBuilder(as.employees),
 	}
 }
 
+// EventFeeds returns the feed of policy membership changes.
+func (as *Expensify) EventFeeds(ctx context.Context) []connectorbuilder.EventFeed {
+	return []connectorbuilder.EventFeed{as.memberships}
+}
+
 // Metadata returns metadata about the connector.
 func (as *Expensify) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
-		DisplayName: "Expensify",
-		Description: "Connector syncing users and policies from Expensify to Baton",
+		DisplayName:           "Expensify",
+		Description:           "Connector syncing users, policies and domains from Expensify to Baton",
+		AccountCreationSchema: accountCreationSchema,
 	}, nil
 }
 
 // Validate hits the Expensify API to validate API credentials.
 func (as *Expensify) Validate(ctx context.Context) (annotations.Annotations, error) {
-	_, err := as.client.GetPolicies(ctx)
+	_, _, err := as.client.GetPolicies(ctx)
 	if err != nil {
+		if errors.Is(err, expensify.ErrAuthentication) {
+			return nil, fmt.Errorf("expensify-connector: invalid partner credentials: %w", err)
+		}
 		return nil, fmt.Errorf("expensify-connector: %w", err)
 	}
 	return nil, nil
 }
 
 // New returns the Expensify connector.
-func New(ctx context.Context, partnerUserID string, partnerUserSecret string) (*Expensify, error) {
-	client, err := expensify.NewClient(ctx, partnerUserID, partnerUserSecret)
+func New(ctx context.Context, ec *cfg.Expensify) (*Expensify, error) {
+	client, err := expensify.NewClient(
+		ctx,
+		ec.PartnerUserId,
+		ec.PartnerUserSecret,
+		expensify.WithMaxRetries(ec.MaxRetries),
+		expensify.WithBaseURL(ec.BaseUrl),
+		expensify.WithProxy(ec.ProxyUrl),
+		expensify.WithCABundle(ec.CaBundlePath),
+		expensify.WithRequestTimeout(time.Duration(ec.RequestTimeout)*time.Second),
+		expensify.WithNonAdminPolicies(ec.IncludeNonAdminPolicies),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create expensify client: %w", err)
 	}
 
+	filter, err := newPolicyFilter(ec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy filter: %w", err)
+	}
+
 	return &Expensify{
-		client: client,
+		client:      client,
+		employees:   newEmployeeCache(client, ec.EmployeeBatchSize, ec.EmployeeConcurrency, filter),
+		memberships: newMembershipFeed(client, ec.EmployeeBatchSize, ec.EmployeeConcurrency, filter, ec.SnapshotPath),
+		pageSize:    ec.PolicyPageSize,
 	}, nil
 }
@@ -0,0 +1,115 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	domainMemberEntitlement = "member"
+	domainAdminEntitlement  = "admin"
+)
+
+type domainResourceType struct {
+	resourceType *v2.ResourceType
+	employees    *employeeCache
+}
+
+func (o *domainResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func domainBuilder(employees *employeeCache) *domainResourceType {
+	return &domainResourceType{
+		resourceType: resourceTypeDomain,
+		employees:    employees,
+	}
+}
+
+// Create a new connector resource for an Expensify domain.
+func domainResource(domain expensify.Domain) (*v2.Resource, error) {
+	return rs.NewResource(
+		domain.Name,
+		resourceTypeDomain,
+		domain.Name,
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeDomainGroup.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeCard.Id},
+		),
+	)
+}
+
+func (o *domainResourceType) List(ctx context.Context, _ *v2.ResourceId, pt *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if pt == nil || pt.Token == "" {
+		o.employees.listStarted()
+	}
+
+	domains, rlData, err := o.employees.domains(ctx)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domains: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, domain := range domains {
+		dr, err := domainResource(domain)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, dr)
+	}
+
+	return rv, "", annos, nil
+}
+
+func (o *domainResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			domainMemberEntitlement,
+			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithDisplayName(fmt.Sprintf("%s Domain Member", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Verified member of the %s Expensify domain", resource.DisplayName)),
+		),
+		ent.NewPermissionEntitlement(
+			resource,
+			domainAdminEntitlement,
+			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithDisplayName(fmt.Sprintf("%s Domain Admin", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Admin of the %s Expensify domain, with control over every account on it", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+func (o *domainResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	o.employees.grantsStarted()
+	members, rlData, err := o.employees.domainMembers(ctx, resource.Id.Resource)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domain members: %w", err)
+	}
+
+	var rv []*v2.Grant
+	for _, member := range members {
+		userID, err := rs.NewResourceID(resourceTypeUser, userResourceID(member.Email))
+		if err != nil {
+			return nil, "", annos, err
+		}
+
+		rv = append(rv, grant.NewGrant(resource, domainMemberEntitlement, userID))
+		if member.IsAdmin {
+			rv = append(rv, grant.NewGrant(resource, domainAdminEntitlement, userID))
+		}
+	}
+
+	return rv, "", annos, nil
+}
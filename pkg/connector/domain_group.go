@@ -0,0 +1,124 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const domainGroupMemberEntitlement = "member"
+
+type domainGroupResourceType struct {
+	resourceType *v2.ResourceType
+	client       *expensify.Client
+}
+
+func (o *domainGroupResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func domainGroupBuilder(client *expensify.Client) *domainGroupResourceType {
+	return &domainGroupResourceType{
+		resourceType: resourceTypeDomainGroup,
+		client:       client,
+	}
+}
+
+// Group IDs are only unique within a domain, so the resource ID is prefixed with the domain.
+func domainGroupID(domain string, groupID string) string {
+	return domain + "/" + groupID
+}
+
+func parseDomainGroupID(id string) (string, string, error) {
+	domain, groupID, ok := strings.Cut(id, "/")
+	if !ok || domain == "" || groupID == "" {
+		return "", "", fmt.Errorf("expensify-connector: invalid domain group id %q", id)
+	}
+	return domain, groupID, nil
+}
+
+// Create a new connector resource for an Expensify domain group.
+func domainGroupResource(domain string, group expensify.DomainGroup, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"domain":            domain,
+		"group_id":          group.ID,
+		"restricted_policy": group.RestrictedPolicy,
+	}
+	setProfileValue(profile, "default_policy_id", group.DefaultPolicyID)
+
+	return rs.NewGroupResource(
+		group.Name,
+		resourceTypeDomainGroup,
+		domainGroupID(domain, group.ID),
+		[]rs.GroupTraitOption{rs.WithGroupProfile(profile)},
+		rs.WithParentResourceID(parentResourceID),
+	)
+}
+
+func (o *domainGroupResourceType) List(ctx context.Context, parentId *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentId == nil {
+		return nil, "", nil, nil
+	}
+
+	groups, rlData, err := o.client.GetDomainGroups(ctx, parentId.Resource)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domain groups: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, group := range groups {
+		gr, err := domainGroupResource(parentId.Resource, group, parentId)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		rv = append(rv, gr)
+	}
+
+	return rv, "", annos, nil
+}
+
+func (o *domainGroupResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			domainGroupMemberEntitlement,
+			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithDisplayName(fmt.Sprintf("%s Domain Group Member", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Member of the %s Expensify domain group", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+func (o *domainGroupResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	domain, groupID, err := parseDomainGroupID(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	members, rlData, err := o.client.GetDomainGroupMembers(ctx, domain, groupID)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domain group members: %w", err)
+	}
+
+	var rv []*v2.Grant
+	for _, member := range members {
+		userID, err := rs.NewResourceID(resourceTypeUser, userResourceID(member.Email))
+		if err != nil {
+			return nil, "", annos, err
+		}
+		rv = append(rv, grant.NewGrant(resource, domainGroupMemberEntitlement, userID))
+	}
+
+	return rv, "", annos, nil
+}
@@ -0,0 +1,434 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultEmployeeBatchSize   = 50
+	defaultEmployeeConcurrency = 4
+)
+
+// employeeCache holds the policies and domains seen during a sync along with their employees and
+// members, so the syncers share one set of requests and one consistent view of memberships.
+//
+// Policies are registered as they are listed and fetched lazily in batches: asking for one policy
+// also prefetches the next pending policies, up to batchSize per request and concurrency requests at once.
+type employeeCache struct {
+	client      *expensify.Client
+	batchSize   int
+	concurrency int
+	filter      *policyFilter
+
+	mtx        sync.Mutex
+	policyList []expensify.Policy
+	listed     bool
+	granting   bool
+	pending    []string
+	employees  map[string][]expensify.User
+	// records indexes the fetched employee records by lowercased email.
+	records map[string][]policyEmployee
+
+	domainList    []expensify.Domain
+	domainsListed bool
+	members       map[string][]expensify.DomainMember
+	cards         map[string][]expensify.Card
+}
+
+func newEmployeeCache(client *expensify.Client, batchSize int, concurrency int, filter *policyFilter) *employeeCache {
+	if batchSize <= 0 {
+		batchSize = defaultEmployeeBatchSize
+	}
+	if concurrency <= 0 {
+		concurrency = defaultEmployeeConcurrency
+	}
+
+	return &employeeCache{
+		client:      client,
+		batchSize:   batchSize,
+		concurrency: concurrency,
+		filter:      filter,
+		employees:   make(map[string][]expensify.User),
+		records:     make(map[string][]policyEmployee),
+		members:     make(map[string][]expensify.DomainMember),
+		cards:       make(map[string][]expensify.Card),
+	}
+}
+
+// listStarted is called on the first page of every List. Resources are listed before any grants, so
+// listing after grants were read means a new sync has started and the cached data is stale.
+func (c *employeeCache) listStarted() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.granting {
+		return
+	}
+
+	c.granting = false
+	c.listed = false
+	c.policyList = nil
+	c.pending = nil
+	c.employees = make(map[string][]expensify.User)
+	c.records = make(map[string][]policyEmployee)
+	c.domainList = nil
+	c.domainsListed = false
+	c.members = make(map[string][]expensify.DomainMember)
+	c.cards = make(map[string][]expensify.Card)
+}
+
+// grantsStarted records that the sync has moved on from listing resources to reading grants.
+func (c *employeeCache) grantsStarted() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.granting = true
+}
+
+// policies returns every policy visible to the credentials that passes the filter, and queues them for
+// employee fetching.
+func (c *employeeCache) policies(ctx context.Context) ([]expensify.Policy, *v2.RateLimitDescription, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.listed {
+		return c.policyList, nil, nil
+	}
+
+	policies, rlData, err := c.client.GetPolicies(ctx)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	c.policyList = sortPolicies(c.filter.apply(policies))
+	c.listed = true
+	for _, policy := range c.policyList {
+		c.registerLocked(policy.ID)
+	}
+
+	return c.policyList, rlData, nil
+}
+
+// policy returns a single policy from the cached policy list, or straight from Expensify if it isn't
+// cached, e.g. because it was created after the list was.
+func (c *employeeCache) policy(ctx context.Context, policyID string) (expensify.Policy, error) {
+	policies, _, err := c.policies(ctx)
+	if err != nil {
+		return expensify.Policy{}, err
+	}
+
+	for _, policy := range policies {
+		if policy.ID == policyID {
+			return policy, nil
+		}
+	}
+
+	policy, _, err := c.client.GetPolicy(ctx, policyID)
+	if err != nil {
+		return expensify.Policy{}, err
+	}
+	return *policy, nil
+}
+
+// policyEmployee is an employee record together with the policy it came from.
+type policyEmployee struct {
+	policyID string
+	expensify.User
+}
+
+// userRecords returns the employee records of the user in every policy they belong to, ordered by policy.
+// Every pending policy is fetched first, so the records come from the index.
+func (c *employeeCache) userRecords(ctx context.Context, email string) ([]policyEmployee, *v2.RateLimitDescription, error) {
+	_, rlData, err := c.policies(ctx)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	for {
+		c.mtx.Lock()
+		if len(c.pending) == 0 {
+			rv := slices.Clone(c.records[strings.ToLower(email)])
+			c.mtx.Unlock()
+			slices.SortFunc(rv, func(a, b policyEmployee) int {
+				return strings.Compare(a.policyID, b.policyID)
+			})
+			return rv, rlData, nil
+		}
+		policyID := c.pending[0]
+		c.mtx.Unlock()
+
+		_, policyRlData, err := c.policyEmployees(ctx, policyID)
+		if policyRlData != nil {
+			rlData = policyRlData
+		}
+		if err != nil {
+			return nil, rlData, err
+		}
+	}
+}
+
+// registerLocked queues policies so they are fetched together with the next cache miss. The caller must hold mtx.
+func (c *employeeCache) registerLocked(policyIDs ...string) {
+	for _, policyID := range policyIDs {
+		if _, ok := c.employees[policyID]; ok || slices.Contains(c.pending, policyID) {
+			continue
+		}
+		c.pending = append(c.pending, policyID)
+	}
+}
+
+// storeLocked caches the employees of a policy and indexes their records, replacing any cached before.
+// The caller must hold mtx.
+func (c *employeeCache) storeLocked(policyID string, employees []expensify.User) {
+	c.dropLocked(policyID)
+	c.employees[policyID] = employees
+	for _, employee := range employees {
+		email := strings.ToLower(employee.Email)
+		c.records[email] = append(c.records[email], policyEmployee{policyID: policyID, User: employee})
+	}
+}
+
+// dropLocked forgets the cached employees of a policy and their indexed records. The caller must hold mtx.
+func (c *employeeCache) dropLocked(policyID string) {
+	for _, employee := range c.employees[policyID] {
+		email := strings.ToLower(employee.Email)
+		c.records[email] = slices.DeleteFunc(c.records[email], func(record policyEmployee) bool {
+			return record.policyID == policyID
+		})
+		if len(c.records[email]) == 0 {
+			delete(c.records, email)
+		}
+	}
+	delete(c.employees, policyID)
+}
+
+// invalidate forgets the cached employees of the policies after they were changed or refreshed, so
+// the next read fetches them again.
+func (c *employeeCache) invalidate(policyIDs ...string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, policyID := range policyIDs {
+		c.invalidateLocked(policyID)
+	}
+}
+
+// invalidateLocked forgets the cached employees of a listed policy and queues it to be fetched again.
+// The caller must hold mtx.
+func (c *employeeCache) invalidateLocked(policyID string) {
+	c.dropLocked(policyID)
+	if slices.ContainsFunc(c.policyList, func(policy expensify.Policy) bool { return policy.ID == policyID }) {
+		c.registerLocked(policyID)
+	}
+}
+
+// refresh replaces the cached record of a policy with one fetched outside of the cache, and forgets
+// its employees so they are fetched again too.
+func (c *employeeCache) refresh(policy expensify.Policy) {
+	c.mtx.Lock()
+	for i := range c.policyList {
+		if c.policyList[i].ID == policy.ID {
+			c.policyList[i] = policy
+		}
+	}
+	c.mtx.Unlock()
+
+	c.invalidate(policy.ID)
+}
+
+// refreshUser replaces the cached employees of the policies the user belongs to, according to either
+// the cache or the employees fetched outside of it. Other policies, the policy list and the pending
+// queue are left alone, so a List paging through the cache isn't disturbed.
+func (c *employeeCache) refreshUser(email string, employees map[string][]expensify.User) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var policyIDs []string
+	for _, record := range c.records[userResourceID(email)] {
+		policyIDs = append(policyIDs, record.policyID)
+	}
+	for policyID, policyEmployees := range employees {
+		if _, ok := findEmployee(policyEmployees, email); ok && !slices.Contains(policyIDs, policyID) {
+			policyIDs = append(policyIDs, policyID)
+		}
+	}
+
+	for _, policyID := range policyIDs {
+		policyEmployees, ok := employees[policyID]
+		if !ok {
+			c.invalidateLocked(policyID)
+			continue
+		}
+		c.storeLocked(policyID, policyEmployees)
+		c.pending = slices.DeleteFunc(c.pending, func(id string) bool { return id == policyID })
+	}
+}
+
+// sortPolicies orders the policies by ID. Paged listings keep their offset in the token, so the order
+// must be stable across runs.
+func sortPolicies(policies []expensify.Policy) []expensify.Policy {
+	slices.SortFunc(policies, func(a, b expensify.Policy) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return policies
+}
+
+// policyEmployees returns the employees of a policy, fetching it along with pending policies on a miss.
+func (c *employeeCache) policyEmployees(ctx context.Context, policyID string) ([]expensify.User, *v2.RateLimitDescription, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if employees, ok := c.employees[policyID]; ok {
+		return employees, nil, nil
+	}
+
+	policyIDs := []string{policyID}
+	for _, pendingID := range c.pending {
+		if len(policyIDs) >= c.batchSize*c.concurrency {
+			break
+		}
+		if pendingID != policyID {
+			policyIDs = append(policyIDs, pendingID)
+		}
+	}
+
+	employees, rlData, err := fetchEmployees(ctx, c.client, policyIDs, c.batchSize, c.concurrency)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	for id, policyEmployees := range employees {
+		c.storeLocked(id, policyEmployees)
+	}
+	c.pending = slices.DeleteFunc(c.pending, func(id string) bool {
+		_, ok := c.employees[id]
+		return ok
+	})
+
+	return c.employees[policyID], rlData, nil
+}
+
+// fetchEmployees fetches the employees of the policies in batches of batchSize, running up to
+// concurrency requests at once. The rate limit data returned is that of the last batch, regardless
+// of the order the requests complete in.
+func fetchEmployees(
+	ctx context.Context,
+	client *expensify.Client,
+	policyIDs []string,
+	batchSize int,
+	concurrency int,
+) (map[string][]expensify.User, *v2.RateLimitDescription, error) {
+	var batches [][]string
+	for batch := range slices.Chunk(policyIDs, batchSize) {
+		batches = append(batches, batch)
+	}
+
+	results := make([]map[string][]expensify.User, len(batches))
+	rlData := make([]*v2.RateLimitDescription, len(batches))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for i, batch := range batches {
+		g.Go(func() error {
+			var err error
+			results[i], rlData[i], err = client.GetPoliciesEmployees(gctx, batch)
+			return err
+		})
+	}
+	err := g.Wait()
+
+	var lastRlData *v2.RateLimitDescription
+	for _, batchRlData := range rlData {
+		if batchRlData != nil {
+			lastRlData = batchRlData
+		}
+	}
+	if err != nil {
+		return nil, lastRlData, err
+	}
+
+	employees := make(map[string][]expensify.User, len(policyIDs))
+	for _, result := range results {
+		maps.Copy(employees, result)
+	}
+
+	return employees, lastRlData, nil
+}
+
+// domainsUnavailable reports whether listing the domains failed because the credentials can't see any,
+// which is the case for partners that administer policies but no domain. It only applies to the domain
+// list: once a domain is listed, a failure to read its members, groups or cards fails the sync, so no
+// domain is ever synced without its admins.
+func domainsUnavailable(err error) bool {
+	return errors.Is(err, expensify.ErrAuthentication) || errors.Is(err, expensify.ErrNotFound)
+}
+
+// domains returns every domain the credentials administer, or none if the domain list is unavailable.
+func (c *employeeCache) domains(ctx context.Context) ([]expensify.Domain, *v2.RateLimitDescription, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.domainsListed {
+		return c.domainList, nil, nil
+	}
+
+	domains, rlData, err := c.client.GetDomains(ctx)
+	if err != nil {
+		if !domainsUnavailable(err) {
+			return nil, rlData, err
+		}
+		ctxzap.Extract(ctx).Warn("Expensify domains are unavailable to the credentials, skipping them", zap.Error(err))
+		domains = nil
+	}
+
+	c.domainList = domains
+	c.domainsListed = true
+
+	return c.domainList, rlData, nil
+}
+
+// domainMembers returns the members of a domain, including its admins.
+func (c *employeeCache) domainMembers(ctx context.Context, domain string) ([]expensify.DomainMember, *v2.RateLimitDescription, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if members, ok := c.members[domain]; ok {
+		return members, nil, nil
+	}
+
+	members, rlData, err := c.client.GetDomainMembers(ctx, domain)
+	if err != nil {
+		return nil, rlData, err
+	}
+	c.members[domain] = members
+
+	return members, rlData, nil
+}
+
+// domainCards returns the company cards assigned on a domain.
+func (c *employeeCache) domainCards(ctx context.Context, domain string) ([]expensify.Card, *v2.RateLimitDescription, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if cards, ok := c.cards[domain]; ok {
+		return cards, nil, nil
+	}
+
+	cards, rlData, err := c.client.GetDomainCards(ctx, domain)
+	if err != nil {
+		return nil, rlData, err
+	}
+	c.cards[domain] = cards
+
+	return cards, rlData, nil
+}
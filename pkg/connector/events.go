@@ -0,0 +1,417 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+const membershipFeedID = "expensify_policy_memberships"
+
+// maxSnapshots bounds how many baselines are kept for consumers whose cursors name snapshots that are gone.
+const maxSnapshots = 32
+
+// policySnapshot is the state of a policy as recorded in a snapshot.
+type policySnapshot struct {
+	Name string `json:"name"`
+	// Members maps the email of every employee to their role.
+	Members map[string]string `json:"members"`
+}
+
+// membershipSnapshot is the policy memberships and roles at a point in time. Its TakenAt is the cursor
+// handed to the consumer.
+type membershipSnapshot struct {
+	TakenAt  time.Time                 `json:"taken_at"`
+	Policies map[string]policySnapshot `json:"policies"`
+}
+
+// snapshotStore is the content of the snapshot file, the snapshots oldest first.
+type snapshotStore struct {
+	Snapshots []membershipSnapshot `json:"snapshots"`
+}
+
+// membershipFeed derives events from policy memberships, since Expensify has no audit log. The events
+// of a page are the difference between the snapshot named by the cursor and the one after it, which is
+// taken on demand. Snapshots are only dropped once the consumer resumes from a later cursor, so a page
+// that was lost or retried is computed again from the same pair and yields the same events.
+type membershipFeed struct {
+	client       *expensify.Client
+	batchSize    int
+	concurrency  int
+	filter       *policyFilter
+	snapshotPath string
+
+	mtx sync.Mutex
+}
+
+func newMembershipFeed(
+	client *expensify.Client,
+	batchSize int,
+	concurrency int,
+	filter *policyFilter,
+	snapshotPath string,
+) *membershipFeed {
+	if batchSize <= 0 {
+		batchSize = defaultEmployeeBatchSize
+	}
+	if concurrency <= 0 {
+		concurrency = defaultEmployeeConcurrency
+	}
+	if snapshotPath == "" {
+		snapshotPath = defaultSnapshotPath()
+	}
+
+	return &membershipFeed{
+		client:       client,
+		batchSize:    batchSize,
+		concurrency:  concurrency,
+		filter:       filter,
+		snapshotPath: snapshotPath,
+	}
+}
+
+// defaultSnapshotPath keeps the snapshots in the user cache directory, falling back to the temp directory.
+func defaultSnapshotPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "baton-expensify", "policy-snapshot.json")
+}
+
+func (f *membershipFeed) EventFeedMetadata(_ context.Context) *v2.EventFeedMetadata {
+	return &v2.EventFeedMetadata{
+		Id:                  membershipFeedID,
+		SupportedEventTypes: []v2.EventType{v2.EventType_EVENT_TYPE_UNSPECIFIED},
+	}
+}
+
+// ListEvents reports the memberships added, removed and changed between the snapshot named by the
+// cursor and the next one. Without a cursor it starts from the last snapshot taken at or before
+// earliestEvent, or the latest snapshot if there is no earliestEvent. When there is no snapshot to
+// start from, it only records a baseline.
+func (f *membershipFeed) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	store, err := f.loadStore()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("expensify-connector: failed to load membership snapshots: %w", err)
+	}
+
+	start := -1
+	if pToken != nil && pToken.Cursor != "" {
+		takenAt, err := time.Parse(time.RFC3339Nano, pToken.Cursor)
+		if err != nil {
+			return nil, nil, nil, status.Errorf(codes.InvalidArgument, "expensify-connector: invalid event feed cursor %q", pToken.Cursor)
+		}
+		start = slices.IndexFunc(store.Snapshots, func(snapshot membershipSnapshot) bool {
+			return snapshot.TakenAt.Equal(takenAt)
+		})
+		if start < 0 {
+			ctxzap.Extract(ctx).Warn("The membership snapshot named by the cursor is gone, recording a new baseline",
+				zap.String("cursor", pToken.Cursor),
+				zap.String("snapshot_path", f.snapshotPath),
+			)
+		}
+	} else {
+		start = startingSnapshot(store.Snapshots, earliestEvent)
+	}
+
+	if start < 0 {
+		current, rlData, err := f.takeSnapshot(ctx)
+		annos := annotationsWithRateLimit(rlData)
+		if err != nil {
+			return nil, nil, annos, fmt.Errorf("expensify-connector: failed to snapshot policy memberships: %w", err)
+		}
+		ctxzap.Extract(ctx).Info("No membership snapshot to start from, recording a baseline",
+			zap.String("snapshot_path", f.snapshotPath),
+		)
+
+		store.Snapshots = append(store.Snapshots, *current)
+		if len(store.Snapshots) > maxSnapshots {
+			store.Snapshots = store.Snapshots[len(store.Snapshots)-maxSnapshots:]
+		}
+		if err := f.saveStore(store); err != nil {
+			return nil, nil, annos, fmt.Errorf("expensify-connector: failed to save membership snapshots: %w", err)
+		}
+		return nil, &pagination.StreamState{Cursor: current.TakenAt.Format(time.RFC3339Nano)}, annos, nil
+	}
+
+	// The consumer has stored everything up to the starting snapshot, so older ones are no longer needed.
+	store.Snapshots = store.Snapshots[start:]
+	var annos annotations.Annotations
+	if len(store.Snapshots) == 1 {
+		current, rlData, err := f.takeSnapshot(ctx)
+		annos = annotationsWithRateLimit(rlData)
+		if err != nil {
+			return nil, nil, annos, fmt.Errorf("expensify-connector: failed to snapshot policy memberships: %w", err)
+		}
+		store.Snapshots = append(store.Snapshots, *current)
+	}
+	if err := f.saveStore(store); err != nil {
+		return nil, nil, annos, fmt.Errorf("expensify-connector: failed to save membership snapshots: %w", err)
+	}
+
+	previous, current := &store.Snapshots[0], &store.Snapshots[1]
+	var events []*v2.Event
+	if earliestEvent == nil || !current.TakenAt.Before(earliestEvent.AsTime()) {
+		events, err = membershipEvents(ctx, previous, current)
+		if err != nil {
+			return nil, nil, annos, err
+		}
+	}
+
+	return events, &pagination.StreamState{
+		Cursor:  current.TakenAt.Format(time.RFC3339Nano),
+		HasMore: len(store.Snapshots) > 2,
+	}, annos, nil
+}
+
+// startingSnapshot returns the index of the last snapshot taken at or before earliestEvent, the first
+// snapshot if all are later, or the latest snapshot without an earliestEvent. It returns -1 if there
+// are no snapshots.
+func startingSnapshot(snapshots []membershipSnapshot, earliestEvent *timestamppb.Timestamp) int {
+	if len(snapshots) == 0 {
+		return -1
+	}
+	if earliestEvent == nil {
+		return len(snapshots) - 1
+	}
+
+	earliest := earliestEvent.AsTime()
+	for i := len(snapshots) - 1; i > 0; i-- {
+		if !snapshots[i].TakenAt.After(earliest) {
+			return i
+		}
+	}
+	return 0
+}
+
+// takeSnapshot records the current memberships of every policy.
+func (f *membershipFeed) takeSnapshot(ctx context.Context) (*membershipSnapshot, *v2.RateLimitDescription, error) {
+	policyList, rlData, err := f.client.GetPolicies(ctx)
+	if err != nil {
+		return nil, rlData, err
+	}
+	policyList = f.filter.apply(policyList)
+
+	policyIDs := make([]string, 0, len(policyList))
+	for _, policy := range policyList {
+		policyIDs = append(policyIDs, policy.ID)
+	}
+
+	employees, employeesRlData, err := fetchEmployees(ctx, f.client, policyIDs, f.batchSize, f.concurrency)
+	if employeesRlData != nil {
+		rlData = employeesRlData
+	}
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	snapshot := &membershipSnapshot{
+		TakenAt:  time.Now().UTC(),
+		Policies: make(map[string]policySnapshot, len(policyList)),
+	}
+	for _, policy := range policyList {
+		members := make(map[string]string, len(employees[policy.ID]))
+		for _, employee := range employees[policy.ID] {
+			if employee.Role != "" {
+				members[userResourceID(employee.Email)] = employee.Role
+			}
+		}
+		snapshot.Policies[policy.ID] = policySnapshot{Name: policy.Name, Members: members}
+	}
+
+	return snapshot, rlData, nil
+}
+
+// membershipChange is the kind of membership change an event belongs to.
+type membershipChange string
+
+const (
+	membershipAdded   membershipChange = "membership_added"
+	membershipRemoved membershipChange = "membership_removed"
+	roleChanged       membershipChange = "role_changed"
+)
+
+// membershipEvents diffs two snapshots into grant and revoke events. A role change is reported as the
+// revocation of the old role together with the grant of the new one. Every event carries an annotation
+// with the kind of change, so a role change can be told apart from a removal and an addition.
+func membershipEvents(
+	ctx context.Context,
+	previous *membershipSnapshot,
+	current *membershipSnapshot,
+) ([]*v2.Event, error) {
+	occurredAt := timestamppb.New(current.TakenAt)
+	policyIDs := slices.Sorted(maps.Keys(previous.Policies))
+	for policyID := range current.Policies {
+		if _, ok := previous.Policies[policyID]; !ok {
+			policyIDs = append(policyIDs, policyID)
+		}
+	}
+	slices.Sort(policyIDs)
+
+	var rv []*v2.Event
+	for _, policyID := range policyIDs {
+		before := previous.Policies[policyID]
+		after, ok := current.Policies[policyID]
+		policy := expensify.Policy{ID: policyID, Name: after.Name}
+		if !ok {
+			policy.Name = before.Name
+		}
+		pr, err := policyResource(ctx, policy)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			ctxzap.Extract(ctx).Info("Expensify policy is no longer visible, revoking its memberships",
+				zap.String("policy_id", policyID),
+			)
+		}
+
+		emails := slices.Sorted(maps.Keys(before.Members))
+		for email := range after.Members {
+			if _, ok := before.Members[email]; !ok {
+				emails = append(emails, email)
+			}
+		}
+		slices.Sort(emails)
+
+		for _, email := range emails {
+			oldRole, wasMember := before.Members[email]
+			newRole, isMember := after.Members[email]
+			if wasMember && isMember && oldRole == newRole {
+				continue
+			}
+
+			ur, err := userResource(ctx, &expensify.User{Email: email})
+			if err != nil {
+				return nil, err
+			}
+
+			kind := membershipAdded
+			switch {
+			case wasMember && isMember:
+				kind = roleChanged
+			case wasMember:
+				kind = membershipRemoved
+			}
+			eventID := fmt.Sprintf("%s:%s:%s:%d", kind, policyID, email, current.TakenAt.UnixNano())
+
+			change, err := structpb.NewStruct(map[string]interface{}{
+				"change":        string(kind),
+				"policy_id":     policyID,
+				"email":         email,
+				"previous_role": oldRole,
+				"role":          newRole,
+			})
+			if err != nil {
+				return nil, err
+			}
+			var annos annotations.Annotations
+			annos.Append(change)
+
+			if wasMember {
+				rv = append(rv, &v2.Event{
+					Id:          eventID + ":revoke",
+					OccurredAt:  occurredAt,
+					Annotations: annos,
+					Event: &v2.Event_RevokeEvent{
+						RevokeEvent: &v2.RevokeEvent{
+							Entitlement: ent.NewPermissionEntitlement(pr, oldRole),
+							Principal:   ur,
+						},
+					},
+				})
+			}
+			if isMember {
+				rv = append(rv, &v2.Event{
+					Id:          eventID + ":grant",
+					OccurredAt:  occurredAt,
+					Annotations: annos,
+					Event: &v2.Event_GrantEvent{
+						GrantEvent: &v2.GrantEvent{
+							Grant: grant.NewGrant(pr, newRole, ur),
+						},
+					},
+				})
+			}
+		}
+	}
+
+	return rv, nil
+}
+
+// loadStore reads the snapshot file, returning an empty store if there is none yet.
+func (f *membershipFeed) loadStore() (*snapshotStore, error) {
+	data, err := os.ReadFile(f.snapshotPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &snapshotStore{}, nil
+		}
+		return nil, err
+	}
+
+	var store snapshotStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.snapshotPath, err)
+	}
+
+	return &store, nil
+}
+
+// saveStore replaces the snapshot file atomically, so an interrupted run never leaves a partial file.
+func (f *membershipFeed) saveStore(store *snapshotStore) error {
+	data, err := json.Marshal(store)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.snapshotPath)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.snapshotPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.snapshotPath)
+}
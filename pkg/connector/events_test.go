@@ -0,0 +1,250 @@
+package connector
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// eventSummary is the part of an event the tests compare: "<change> <grant|revoke> <entitlement ID> <email>".
+func eventSummary(t *testing.T, event *v2.Event) string {
+	t.Helper()
+
+	change := &structpb.Struct{}
+	for _, annotation := range event.Annotations {
+		if annotation.UnmarshalTo(change) == nil {
+			break
+		}
+	}
+	kind := change.GetFields()["change"].GetStringValue()
+
+	switch e := event.Event.(type) {
+	case *v2.Event_GrantEvent:
+		g := e.GrantEvent.Grant
+		return kind + " grant " + g.Entitlement.Id + " " + g.Principal.Id.Resource
+	case *v2.Event_RevokeEvent:
+		r := e.RevokeEvent
+		return kind + " revoke " + r.Entitlement.Id + " " + r.Principal.Id.Resource
+	default:
+		t.Fatalf("unexpected event %T", event.Event)
+		return ""
+	}
+}
+
+func eventSummaries(t *testing.T, events []*v2.Event) []string {
+	rv := make([]string, 0, len(events))
+	for _, event := range events {
+		rv = append(rv, eventSummary(t, event))
+	}
+	return rv
+}
+
+func TestMembershipEvents(t *testing.T) {
+	takenAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	snapshot := func(policies map[string]policySnapshot) *membershipSnapshot {
+		return &membershipSnapshot{TakenAt: takenAt, Policies: policies}
+	}
+
+	tests := []struct {
+		name     string
+		previous *membershipSnapshot
+		current  *membershipSnapshot
+		want     []string
+	}{
+		{
+			name:     "unchanged",
+			previous: snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{"a@x.com": "user"}}}),
+			current:  snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{"a@x.com": "user"}}}),
+			want:     []string{},
+		},
+		{
+			name:     "added",
+			previous: snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{}}}),
+			current:  snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{"a@x.com": "user"}}}),
+			want:     []string{"membership_added grant policy:P1:user a@x.com"},
+		},
+		{
+			name:     "removed",
+			previous: snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{"a@x.com": "admin"}}}),
+			current:  snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{}}}),
+			want:     []string{"membership_removed revoke policy:P1:admin a@x.com"},
+		},
+		{
+			name:     "role changed",
+			previous: snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{"a@x.com": "user"}}}),
+			current:  snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{"a@x.com": "auditor"}}}),
+			want: []string{
+				"role_changed revoke policy:P1:user a@x.com",
+				"role_changed grant policy:P1:auditor a@x.com",
+			},
+		},
+		{
+			name:     "policy gone",
+			previous: snapshot(map[string]policySnapshot{"P1": {Name: "One", Members: map[string]string{"b@x.com": "user", "a@x.com": "admin"}}}),
+			current:  snapshot(map[string]policySnapshot{}),
+			want: []string{
+				"membership_removed revoke policy:P1:admin a@x.com",
+				"membership_removed revoke policy:P1:user b@x.com",
+			},
+		},
+		{
+			name:     "policy appeared",
+			previous: snapshot(map[string]policySnapshot{"P2": {Name: "Two", Members: map[string]string{}}}),
+			current: snapshot(map[string]policySnapshot{
+				"P1": {Name: "One", Members: map[string]string{"a@x.com": "user"}},
+				"P2": {Name: "Two", Members: map[string]string{"b@x.com": "user"}},
+			}),
+			want: []string{
+				"membership_added grant policy:P1:user a@x.com",
+				"membership_added grant policy:P2:user b@x.com",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := membershipEvents(context.Background(), tt.previous, tt.current)
+			if err != nil {
+				t.Fatalf("membershipEvents: %v", err)
+			}
+			if got := eventSummaries(t, events); !slices.Equal(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+
+			ids := make(map[string]struct{}, len(events))
+			for _, event := range events {
+				if _, ok := ids[event.Id]; ok {
+					t.Errorf("duplicate event ID %q", event.Id)
+				}
+				ids[event.Id] = struct{}{}
+				if !event.OccurredAt.AsTime().Equal(takenAt) {
+					t.Errorf("event %q occurred at %v, want %v", event.Id, event.OccurredAt.AsTime(), takenAt)
+				}
+			}
+		})
+	}
+}
+
+func TestStartingSnapshot(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []membershipSnapshot{
+		{TakenAt: base},
+		{TakenAt: base.Add(time.Hour)},
+		{TakenAt: base.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name          string
+		snapshots     []membershipSnapshot
+		earliestEvent *timestamppb.Timestamp
+		want          int
+	}{
+		{"no snapshots", nil, nil, -1},
+		{"no earliest event", snapshots, nil, 2},
+		{"before every snapshot", snapshots, timestamppb.New(base.Add(-time.Hour)), 0},
+		{"at a snapshot", snapshots, timestamppb.New(base.Add(time.Hour)), 1},
+		{"between snapshots", snapshots, timestamppb.New(base.Add(90 * time.Minute)), 1},
+		{"after every snapshot", snapshots, timestamppb.New(base.Add(3 * time.Hour)), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := startingSnapshot(tt.snapshots, tt.earliestEvent); got != tt.want {
+				t.Errorf("startingSnapshot = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListEventsCursors(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeExpensify{
+		policies: []expensify.Policy{{ID: "P1", Name: "One", Role: "admin"}},
+		employees: map[string][]expensify.User{
+			"P1": {{Email: "a@x.com", Role: "user"}},
+		},
+	}
+	feed := newMembershipFeed(newFakeClient(t, fake), 2, 2, nil, filepath.Join(t.TempDir(), "snapshot.json"))
+
+	listEvents := func(cursor string) ([]string, *pagination.StreamState) {
+		t.Helper()
+		var token *pagination.StreamToken
+		if cursor != "" {
+			token = &pagination.StreamToken{Cursor: cursor}
+		}
+		events, state, _, err := feed.ListEvents(ctx, nil, token)
+		if err != nil {
+			t.Fatalf("ListEvents(%q): %v", cursor, err)
+		}
+		return eventSummaries(t, events), state
+	}
+	snapshotCount := func() int {
+		t.Helper()
+		store, err := feed.loadStore()
+		if err != nil {
+			t.Fatalf("loadStore: %v", err)
+		}
+		return len(store.Snapshots)
+	}
+
+	// The first call only records a baseline.
+	events, baseline := listEvents("")
+	if len(events) != 0 || baseline.Cursor == "" || baseline.HasMore {
+		t.Fatalf("first call = %q, %+v, want a baseline without events", events, baseline)
+	}
+
+	fake.setEmployees("P1", expensify.User{Email: "a@x.com", Role: "admin"}, expensify.User{Email: "b@x.com", Role: "user"})
+	want := []string{
+		"role_changed revoke policy:P1:user a@x.com",
+		"role_changed grant policy:P1:admin a@x.com",
+		"membership_added grant policy:P1:user b@x.com",
+	}
+	events, first := listEvents(baseline.Cursor)
+	if !slices.Equal(events, want) {
+		t.Errorf("events after the baseline = %q, want %q", events, want)
+	}
+	if first.Cursor == baseline.Cursor {
+		t.Error("the cursor did not advance")
+	}
+
+	// A page that is retried from the same cursor yields the same events, even after more changes.
+	fake.setEmployees("P1", expensify.User{Email: "b@x.com", Role: "user"})
+	events, retried := listEvents(baseline.Cursor)
+	if !slices.Equal(events, want) || retried.Cursor != first.Cursor {
+		t.Errorf("retried page = %q, %q, want %q, %q", events, retried.Cursor, want, first.Cursor)
+	}
+
+	// Resuming from the later cursor drops the baseline and reports the next changes.
+	events, second := listEvents(first.Cursor)
+	if want := []string{"membership_removed revoke policy:P1:admin a@x.com"}; !slices.Equal(events, want) {
+		t.Errorf("events after the first page = %q, want %q", events, want)
+	}
+	if n := snapshotCount(); n != 2 {
+		t.Errorf("%d snapshots kept, want 2", n)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, second.Cursor); err != nil {
+		t.Errorf("cursor %q is not a timestamp: %v", second.Cursor, err)
+	}
+
+	// A cursor naming a snapshot that is gone records a new baseline.
+	events, rebased := listEvents(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339Nano))
+	if len(events) != 0 || rebased.Cursor == "" || rebased.HasMore {
+		t.Errorf("unknown cursor = %q, %+v, want a baseline without events", events, rebased)
+	}
+	if n := snapshotCount(); n != 3 {
+		t.Errorf("%d snapshots kept, want 3", n)
+	}
+
+	_, _, _, err := feed.ListEvents(ctx, nil, &pagination.StreamToken{Cursor: "not a time"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("invalid cursor error = %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,125 @@
+package connector
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	cfg "github.com/conductorone/baton-expensify/pkg/config"
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+)
+
+var policyTypes = []string{"corporate", "team", "personal"}
+
+// policyFilter limits the synced policies by ID, name and type. A policy is synced if it matches every
+// configured include list and none of the exclude lists.
+type policyFilter struct {
+	includeIDs   []string
+	excludeIDs   []string
+	includeNames []*regexp.Regexp
+	excludeNames []*regexp.Regexp
+	includeTypes []string
+	excludeTypes []string
+}
+
+func newPolicyFilter(ec *cfg.Expensify) (*policyFilter, error) {
+	includeNames, err := compilePatterns(ec.IncludePolicyNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include-policy-names: %w", err)
+	}
+	excludeNames, err := compilePatterns(ec.ExcludePolicyNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude-policy-names: %w", err)
+	}
+	includeTypes, err := normalizePolicyTypes(ec.IncludePolicyTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include-policy-types: %w", err)
+	}
+	excludeTypes, err := normalizePolicyTypes(ec.ExcludePolicyTypes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude-policy-types: %w", err)
+	}
+
+	return &policyFilter{
+		includeIDs:   trimValues(ec.IncludePolicyIds),
+		excludeIDs:   trimValues(ec.ExcludePolicyIds),
+		includeNames: includeNames,
+		excludeNames: excludeNames,
+		includeTypes: includeTypes,
+		excludeTypes: excludeTypes,
+	}, nil
+}
+
+// matches reports whether the policy should be synced.
+func (f *policyFilter) matches(policy expensify.Policy) bool {
+	if f == nil {
+		return true
+	}
+
+	policyType := strings.ToLower(policy.Type)
+	switch {
+	case len(f.includeIDs) > 0 && !slices.Contains(f.includeIDs, policy.ID):
+		return false
+	case slices.Contains(f.excludeIDs, policy.ID):
+		return false
+	case len(f.includeNames) > 0 && !matchesAny(f.includeNames, policy.Name):
+		return false
+	case matchesAny(f.excludeNames, policy.Name):
+		return false
+	case len(f.includeTypes) > 0 && !slices.Contains(f.includeTypes, policyType):
+		return false
+	case slices.Contains(f.excludeTypes, policyType):
+		return false
+	default:
+		return true
+	}
+}
+
+// apply returns the policies that should be synced, in their original order.
+func (f *policyFilter) apply(policies []expensify.Policy) []expensify.Policy {
+	return slices.DeleteFunc(slices.Clone(policies), func(policy expensify.Policy) bool {
+		return !f.matches(policy)
+	})
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	return slices.ContainsFunc(patterns, func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(s)
+	})
+}
+
+func compilePatterns(values []string) ([]*regexp.Regexp, error) {
+	var rv []*regexp.Regexp
+	for _, value := range trimValues(values) {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, pattern)
+	}
+	return rv, nil
+}
+
+func normalizePolicyTypes(values []string) ([]string, error) {
+	var rv []string
+	for _, value := range trimValues(values) {
+		value = strings.ToLower(value)
+		if !slices.Contains(policyTypes, value) {
+			return nil, fmt.Errorf("unknown policy type %q, use one of %s", value, strings.Join(policyTypes, ", "))
+		}
+		rv = append(rv, value)
+	}
+	return rv, nil
+}
+
+// trimValues trims the values and drops empty ones, which a trailing comma in a flag leaves behind.
+func trimValues(values []string) []string {
+	var rv []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			rv = append(rv, value)
+		}
+	}
+	return rv
+}
@@ -0,0 +1,72 @@
+package connector
+
+import (
+	"testing"
+
+	cfg "github.com/conductorone/baton-expensify/pkg/config"
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+)
+
+func TestPolicyFilterMatches(t *testing.T) {
+	sales := expensify.Policy{ID: "P1", Name: "Sales EMEA", Type: "corporate"}
+	team := expensify.Policy{ID: "P2", Name: "Engineering", Type: "Team"}
+	personal := expensify.Policy{ID: "P3", Name: "Sales personal", Type: "personal"}
+
+	tests := []struct {
+		name   string
+		config cfg.Expensify
+		policy expensify.Policy
+		want   bool
+	}{
+		{"no filters", cfg.Expensify{}, sales, true},
+		{"included id", cfg.Expensify{IncludePolicyIds: []string{"P1", "P9"}}, sales, true},
+		{"id not included", cfg.Expensify{IncludePolicyIds: []string{"P9"}}, sales, false},
+		{"excluded id", cfg.Expensify{ExcludePolicyIds: []string{" P1 "}}, sales, false},
+		{"exclude wins over include", cfg.Expensify{IncludePolicyIds: []string{"P1"}, ExcludePolicyIds: []string{"P1"}}, sales, false},
+		{"included name", cfg.Expensify{IncludePolicyNames: []string{"^Sales"}}, sales, true},
+		{"name not included", cfg.Expensify{IncludePolicyNames: []string{"^Sales"}}, team, false},
+		{"excluded name", cfg.Expensify{ExcludePolicyNames: []string{"personal$"}}, personal, false},
+		{"included type ignores case", cfg.Expensify{IncludePolicyTypes: []string{"TEAM"}}, team, true},
+		{"type not included", cfg.Expensify{IncludePolicyTypes: []string{"corporate", "team"}}, personal, false},
+		{"excluded type", cfg.Expensify{ExcludePolicyTypes: []string{"personal"}}, personal, false},
+		{"every include list must match", cfg.Expensify{IncludePolicyNames: []string{"^Sales"}, IncludePolicyTypes: []string{"team"}}, sales, false},
+		{"empty values are ignored", cfg.Expensify{IncludePolicyIds: []string{"", " "}}, sales, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newPolicyFilter(&tt.config)
+			if err != nil {
+				t.Fatalf("newPolicyFilter: %v", err)
+			}
+			if got := filter.matches(tt.policy); got != tt.want {
+				t.Errorf("matches(%+v) = %v, want %v", tt.policy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyFilterNil(t *testing.T) {
+	var filter *policyFilter
+	if !filter.matches(expensify.Policy{ID: "P1"}) {
+		t.Error("a nil filter must match every policy")
+	}
+}
+
+func TestNewPolicyFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config cfg.Expensify
+	}{
+		{"invalid include pattern", cfg.Expensify{IncludePolicyNames: []string{"("}}},
+		{"invalid exclude pattern", cfg.Expensify{ExcludePolicyNames: []string{"[a-"}}},
+		{"unknown include type", cfg.Expensify{IncludePolicyTypes: []string{"enterprise"}}},
+		{"unknown exclude type", cfg.Expensify{ExcludePolicyTypes: []string{"free"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newPolicyFilter(&tt.config); err == nil {
+				t.Error("newPolicyFilter succeeded, want an error")
+			}
+		})
+	}
+}
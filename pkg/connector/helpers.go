@@ -1,12 +1,119 @@
 package connector
 
 import (
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
 )
 
-func annotationsForUserResourceType() annotations.Annotations {
+// defaultPageSize is how many policies a page walks through when no page size is configured.
+const defaultPageSize = 100
+
+// annotationsWithRateLimit wraps the rate limit data reported by the client, if any.
+func annotationsWithRateLimit(rlData *v2.RateLimitDescription) annotations.Annotations {
 	annos := annotations.Annotations{}
-	annos.Update(&v2.SkipEntitlementsAndGrants{})
+	if rlData != nil {
+		annos.WithRateLimiting(rlData)
+	}
 	return annos
 }
+
+// setProfileValue adds value to the profile under key, skipping empty strings.
+func setProfileValue(profile map[string]interface{}, key string, value string) {
+	if value != "" {
+		profile[key] = value
+	}
+}
+
+// entitlementSlug returns the slug of the entitlement, falling back to the last part of its ID.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	if entitlement.Slug != "" {
+		return entitlement.Slug
+	}
+	parts := strings.Split(entitlement.Id, ":")
+	return parts[len(parts)-1]
+}
+
+// userResourceID is the canonical resource ID of the user with the email. Expensify compares emails
+// case-insensitively, so the ID is lowercased to keep differently cased records of a user together.
+func userResourceID(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// findEmployee returns the employee with the given email, compared case-insensitively.
+func findEmployee(employees []expensify.User, email string) (expensify.User, bool) {
+	for _, employee := range employees {
+		if strings.EqualFold(employee.Email, email) {
+			return employee, true
+		}
+	}
+	return expensify.User{}, false
+}
+
+// profileString returns the trimmed string value of key, or an empty string if it is missing or not a string.
+func profileString(profile map[string]interface{}, key string) string {
+	v, ok := profile[key].(string)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(v)
+}
+
+// profileStringList returns the values of key, which may be a list or a comma separated string.
+func profileStringList(profile map[string]interface{}, key string) []string {
+	var values []string
+	switch v := profile[key].(type) {
+	case string:
+		values = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	var rv []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			rv = append(rv, value)
+		}
+	}
+	return rv
+}
+
+// parsePageToken decodes the pagination bag of a paged List, starting at resourceTypeID on the first page,
+// along with the ID of the last policy finished by the previous pages, which is empty on the first page.
+func parsePageToken(pt *pagination.Token, resourceTypeID string) (*pagination.Bag, string, error) {
+	bag := &pagination.Bag{}
+	if pt != nil {
+		if err := bag.Unmarshal(pt.Token); err != nil {
+			return nil, "", err
+		}
+	}
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeID})
+	}
+
+	return bag, bag.PageToken(), nil
+}
+
+// pageStart returns the index of the first policy after the one with the ID lastPolicyID, in policies
+// sorted by ID. Resuming after an ID rather than at an offset skips nothing when a finished policy is
+// deleted before the next page is read.
+func pageStart(policies []expensify.Policy, lastPolicyID string) int {
+	if lastPolicyID == "" {
+		return 0
+	}
+	i, found := slices.BinarySearchFunc(policies, lastPolicyID, func(policy expensify.Policy, id string) int {
+		return strings.Compare(policy.ID, id)
+	})
+	if found {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,98 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestParsePageToken(t *testing.T) {
+	bag := &pagination.Bag{}
+	bag.Push(pagination.PageState{ResourceTypeID: resourceTypePolicy.Id, Token: "P02"})
+	resumed, err := bag.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domainBag := &pagination.Bag{}
+	domainBag.Push(pagination.PageState{ResourceTypeID: resourceTypeDomain.Id})
+	domainPhase, err := domainBag.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		token            *pagination.Token
+		wantResourceType string
+		wantLastPolicyID string
+		wantErr          bool
+	}{
+		{"nil token", nil, resourceTypePolicy.Id, "", false},
+		{"first page", &pagination.Token{}, resourceTypePolicy.Id, "", false},
+		{"resumed page", &pagination.Token{Token: resumed}, resourceTypePolicy.Id, "P02", false},
+		{"later phase", &pagination.Token{Token: domainPhase}, resourceTypeDomain.Id, "", false},
+		{"garbage", &pagination.Token{Token: "not json"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bag, lastPolicyID, err := parsePageToken(tt.token, resourceTypePolicy.Id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parsePageToken succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePageToken: %v", err)
+			}
+			if got := bag.ResourceTypeID(); got != tt.wantResourceType {
+				t.Errorf("resource type = %q, want %q", got, tt.wantResourceType)
+			}
+			if lastPolicyID != tt.wantLastPolicyID {
+				t.Errorf("last policy ID = %q, want %q", lastPolicyID, tt.wantLastPolicyID)
+			}
+		})
+	}
+}
+
+func TestPageStart(t *testing.T) {
+	policies := []expensify.Policy{{ID: "P01"}, {ID: "P02"}, {ID: "P04"}, {ID: "P05"}}
+
+	tests := []struct {
+		name         string
+		lastPolicyID string
+		want         int
+	}{
+		{"first page", "", 0},
+		{"after a listed policy", "P02", 2},
+		{"after a deleted policy", "P03", 2},
+		{"after the last policy", "P05", 4},
+		{"after a deleted last policy", "P06", 4},
+		{"before every policy", "P00", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageStart(policies, tt.lastPolicyID); got != tt.want {
+				t.Errorf("pageStart(%q) = %d, want %d", tt.lastPolicyID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserResourceID(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"frank@x.com", "frank@x.com"},
+		{"Frank@X.com", "frank@x.com"},
+		{" frank@x.com ", "frank@x.com"},
+	}
+	for _, tt := range tests {
+		if got := userResourceID(tt.email); got != tt.want {
+			t.Errorf("userResourceID(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
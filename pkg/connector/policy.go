@@ -3,6 +3,9 @@ package connector
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/conductorone/baton-expensify/pkg/expensify"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -10,43 +13,68 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 )
 
-var roles = map[string]string{
-	"admin":   "admin",
-	"auditor": "auditor",
-	"user":    "user",
+// policyOwnerEntitlement is held by the policy owner, who has billing authority. Ownership can't be
+// granted or revoked through role provisioning, so its grant is immutable.
+const policyOwnerEntitlement = "owner"
+
+// knownRoles maps the roles Expensify is known to return to curated entitlement descriptions. Any other
+// role found on a policy still gets an entitlement, with a generic description.
+var knownRoles = map[string]string{
+	"admin":   "Admin of %s Expensify policy, with full control over its settings and employees",
+	"auditor": "Auditor of %s Expensify policy, with read access to every report",
+	"user":    "Employee of %s Expensify policy, who submits expense reports",
 }
 
 type policyResourceType struct {
 	resourceType *v2.ResourceType
 	client       *expensify.Client
+	employees    *employeeCache
+	pageSize     int
 }
 
 func (o *policyResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 	return o.resourceType
 }
 
-func policyBuilder(client *expensify.Client) *policyResourceType {
+func policyBuilder(client *expensify.Client, employees *employeeCache, pageSize int) *policyResourceType {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
 	return &policyResourceType{
 		resourceType: resourceTypePolicy,
 		client:       client,
+		employees:    employees,
+		pageSize:     pageSize,
 	}
 }
 
 // Create a new connector resource for an Expensify policy.
 func policyResource(ctx context.Context, policy expensify.Policy) (*v2.Resource, error) {
-	policyOptions := []rs.ResourceOption{
-		rs.WithAnnotation(
-			&v2.ChildResourceType{ResourceTypeId: resourceTypeUser.Id},
-		),
+	profile := map[string]interface{}{
+		"policy_id": policy.ID,
 	}
+	setProfileValue(profile, "type", policy.Type)
+	setProfileValue(profile, "output_currency", policy.OutputCurrency)
+	setProfileValue(profile, "owner", policy.Owner)
+	setProfileValue(profile, "role", policy.Role)
+	profile["read_only"] = policyReadOnly(policy)
 
-	ret, err := rs.NewResource(policy.Name, resourceTypePolicy, policy.ID, policyOptions...)
+	ret, err := rs.NewGroupResource(
+		policy.Name,
+		resourceTypePolicy,
+		policy.ID,
+		[]rs.GroupTraitOption{rs.WithGroupProfile(profile)},
+		rs.WithDescription(policyDescription(policy)),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -54,15 +82,50 @@ func policyResource(ctx context.Context, policy expensify.Policy) (*v2.Resource,
 	return ret, nil
 }
 
+// policyReadOnly reports whether the partner user only audits or belongs to the policy, so its roles
+// can't be changed through the connector.
+func policyReadOnly(policy expensify.Policy) bool {
+	return policy.Role != "" && policy.Role != "admin"
+}
+
+// policyDescription summarizes the kind of policy, e.g. "Corporate policy reporting in USD, owned by owner@example.com".
+func policyDescription(policy expensify.Policy) string {
+	policyType := "Expensify"
+	if policy.Type != "" {
+		policyType = strings.ToUpper(policy.Type[:1]) + policy.Type[1:]
+	}
+
+	description := policyType + " policy"
+	if policy.OutputCurrency != "" {
+		description += " reporting in " + policy.OutputCurrency
+	}
+	if policy.Owner != "" {
+		description += ", owned by " + policy.Owner
+	}
+	return description
+}
+
+// List returns the policies in chunks of pageSize, with the ID of the last policy of the chunk in the token.
 func (o *policyResourceType) List(ctx context.Context, resourceId *v2.ResourceId, pt *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	var rv []*v2.Resource
-	policies, err := o.client.GetPolicies(ctx)
+	if pt == nil || pt.Token == "" {
+		o.employees.listStarted()
+	}
 
+	bag, lastPolicyID, err := parsePageToken(pt, resourceTypePolicy.Id)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for _, policy := range policies {
+	policies, rlData, err := o.employees.policies(ctx)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	start := pageStart(policies, lastPolicyID)
+	end := min(start+o.pageSize, len(policies))
+	var rv []*v2.Resource
+	for _, policy := range policies[start:end] {
 		pr, err := policyResource(ctx, policy)
 		if err != nil {
 			return nil, "", nil, err
@@ -70,43 +133,93 @@ func (o *policyResourceType) List(ctx context.Context, resourceId *v2.ResourceId
 		rv = append(rv, pr)
 	}
 
-	return rv, "", nil, nil
+	next := ""
+	if end < len(policies) {
+		next = policies[end-1].ID
+	}
+	nextToken, err := bag.NextToken(next)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	return rv, nextToken, annos, nil
+}
+
+// policyRoles returns the known roles along with any other role held by an employee, in a stable order.
+func policyRoles(employees []expensify.User) []string {
+	found := make(map[string]struct{}, len(knownRoles))
+	for role := range knownRoles {
+		found[role] = struct{}{}
+	}
+	for _, employee := range employees {
+		if employee.Role != "" {
+			found[employee.Role] = struct{}{}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(found))
 }
 
 func (o *policyResourceType) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	policyEmployees, rlData, err := o.employees.policyEmployees(ctx, resource.Id.Resource)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
 	var rv []*v2.Entitlement
-	for _, role := range roles {
+	for _, role := range policyRoles(policyEmployees) {
+		var description string
+		if format, ok := knownRoles[role]; ok {
+			description = fmt.Sprintf(format, resource.DisplayName)
+		} else {
+			ctxzap.Extract(ctx).Info("Unknown Expensify role name, using a generic entitlement",
+				zap.String("role_name", role),
+				zap.String("policy_id", resource.Id.Resource),
+			)
+			description = fmt.Sprintf("%s role in %s Expensify policy", role, resource.DisplayName)
+		}
+
 		permissionOptions := []ent.EntitlementOption{
 			ent.WithGrantableTo(resourceTypeUser),
-			ent.WithDescription(fmt.Sprintf("Role in %s Expensify policy", resource.DisplayName)),
+			ent.WithDescription(description),
 			ent.WithDisplayName(fmt.Sprintf("%s Policy %s", resource.DisplayName, role)),
 		}
 
 		permissionEn := ent.NewPermissionEntitlement(resource, role, permissionOptions...)
 		rv = append(rv, permissionEn)
 	}
-	return rv, "", nil, nil
+
+	rv = append(rv, ent.NewPermissionEntitlement(
+		resource,
+		policyOwnerEntitlement,
+		ent.WithGrantableTo(resourceTypeUser),
+		ent.WithDescription(fmt.Sprintf("Owner of %s Expensify policy, with billing authority", resource.DisplayName)),
+		ent.WithDisplayName(fmt.Sprintf("%s Policy %s", resource.DisplayName, policyOwnerEntitlement)),
+	))
+
+	return rv, "", annos, nil
 }
 
 func (o *policyResourceType) Grants(ctx context.Context, resource *v2.Resource, pt *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	policyEmployees, err := o.client.GetPolicyEmployees(ctx, resource.Id.Resource)
-
+	o.employees.grantsStarted()
+	policyEmployees, rlData, err := o.employees.policyEmployees(ctx, resource.Id.Resource)
+	annos := annotationsWithRateLimit(rlData)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", annos, err
 	}
 
 	var rv []*v2.Grant
 	for _, policyEmployee := range policyEmployees {
-		roleName, ok := roles[policyEmployee.Role]
-		if !ok {
-			ctxzap.Extract(ctx).Warn("Unknown Expensify Role Name, skipping",
-				zap.String("role_name", policyEmployee.Role),
+		roleName := policyEmployee.Role
+		if roleName == "" {
+			ctxzap.Extract(ctx).Warn("Expensify employee has no role, skipping",
 				zap.String("user", policyEmployee.Email),
 			)
 			continue
 		}
 		policyEmployeeCopy := policyEmployee
-		ur, err := userResource(ctx, &policyEmployeeCopy, resource.Id)
+		ur, err := userResource(ctx, &policyEmployeeCopy)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -115,5 +228,230 @@ func (o *policyResourceType) Grants(ctx context.Context, resource *v2.Resource,
 		rv = append(rv, permissionGrant)
 	}
 
-	return rv, "", nil, nil
+	policy, err := o.employees.policy(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", annos, err
+	}
+	if policy.Owner != "" {
+		ownerID, err := rs.NewResourceID(resourceTypeUser, userResourceID(policy.Owner))
+		if err != nil {
+			return nil, "", annos, err
+		}
+		rv = append(rv, grant.NewGrant(resource, policyOwnerEntitlement, ownerID, grant.WithAnnotation(&v2.GrantImmutable{})))
+	}
+
+	return rv, "", annos, nil
+}
+
+// Grant adds the user to the policy with the entitlement's role, or changes their role if they are already an employee.
+func (o *policyResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, nil, fmt.Errorf("expensify-connector: only users can be granted policy roles, got %s", principal.Id.ResourceType)
+	}
+
+	policyID := entitlement.Resource.Id.Resource
+	email := principal.Id.Resource
+	role := entitlementSlug(entitlement)
+	if role == policyOwnerEntitlement {
+		return nil, nil, fmt.Errorf("expensify-connector: the policy owner can't be granted, transfer ownership instead")
+	}
+	if err := o.requireAdmin(ctx, policyID); err != nil {
+		return nil, nil, err
+	}
+
+	employees, rlData, err := o.client.GetPolicyEmployees(ctx, policyID)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to get policy employees: %w", err)
+	}
+
+	managerEmail := ""
+	if employee, ok := findEmployee(employees, email); ok {
+		if employee.Role == role {
+			annos.Update(&v2.GrantAlreadyExists{})
+			return nil, annos, nil
+		}
+		managerEmail = employee.SubmitsTo
+	}
+
+	// New employees must submit to someone, default to the policy owner.
+	if managerEmail == "" {
+		policy, err := o.employees.policy(ctx, policyID)
+		if err != nil {
+			return nil, annos, fmt.Errorf("expensify-connector: failed to get policy: %w", err)
+		}
+		managerEmail = policy.Owner
+	}
+
+	rlData, err = o.client.SetEmployeeRole(ctx, policyID, email, role, managerEmail)
+	o.employees.invalidate(policyID)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to grant policy role: %w", err)
+	}
+
+	return []*v2.Grant{grant.NewGrant(entitlement.Resource, role, principal.Id)}, annos, nil
+}
+
+// requireAdmin fails if the policy is synced read-only because the partner user isn't one of its admins.
+func (o *policyResourceType) requireAdmin(ctx context.Context, policyID string) error {
+	policy, err := o.employees.policy(ctx, policyID)
+	if err != nil {
+		return fmt.Errorf("expensify-connector: failed to get policy: %w", err)
+	}
+	return writablePolicy(policy)
+}
+
+// writablePolicy fails if the policy is read-only.
+func writablePolicy(policy expensify.Policy) error {
+	if policyReadOnly(policy) {
+		return status.Errorf(codes.FailedPrecondition, "expensify-connector: policy %s is read-only, the partner user is only its %s", policy.ID, policy.Role)
+	}
+	return nil
+}
+
+// Revoke removes the user from the policy, unless their role has already changed to something other than the revoked one.
+func (o *policyResourceType) Revoke(ctx context.Context, g *v2.Grant) (annotations.Annotations, error) {
+	entitlement := g.Entitlement
+	principal := g.Principal
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("expensify-connector: only users can have policy roles revoked, got %s", principal.Id.ResourceType)
+	}
+
+	policyID := entitlement.Resource.Id.Resource
+	email := principal.Id.Resource
+	role := entitlementSlug(entitlement)
+	if role == policyOwnerEntitlement {
+		return nil, fmt.Errorf("expensify-connector: the policy owner can't be revoked, transfer ownership instead")
+	}
+	if err := o.requireAdmin(ctx, policyID); err != nil {
+		return nil, err
+	}
+
+	employees, rlData, err := o.client.GetPolicyEmployees(ctx, policyID)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return annos, fmt.Errorf("expensify-connector: failed to get policy employees: %w", err)
+	}
+
+	employee, ok := findEmployee(employees, email)
+	if !ok || employee.Role != role {
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	rlData, err = o.client.RemoveEmployee(ctx, policyID, email)
+	o.employees.invalidate(policyID)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return annos, fmt.Errorf("expensify-connector: failed to revoke policy role: %w", err)
+	}
+
+	return annos, nil
+}
+
+// Get refreshes a single policy straight from Expensify. A targeted sync reads the grants right after
+// Get without listing first, so the cached policy and its employees are refreshed too.
+func (o *policyResourceType) Get(ctx context.Context, resourceId *v2.ResourceId, _ *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	policy, rlData, err := o.client.GetPolicy(ctx, resourceId.Resource)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to get policy: %w", err)
+	}
+	if !o.employees.filter.matches(*policy) {
+		return nil, annos, status.Errorf(codes.NotFound, "expensify-connector: policy %s is excluded by the policy filters", policy.ID)
+	}
+	o.employees.refresh(*policy)
+
+	pr, err := policyResource(ctx, *policy)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return pr, annos, nil
+}
+
+// Create creates a policy named after the resource through the policy creator job, sets its output
+// currency and invites the initial admins. The group profile may hold "type" ("corporate" or "team",
+// defaulting to corporate), "output_currency" and "admins".
+func (o *policyResourceType) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	name := strings.TrimSpace(resource.GetDisplayName())
+	if name == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "expensify-connector: a policy name is required")
+	}
+
+	var profile map[string]interface{}
+	if groupTrait, err := rs.GetGroupTrait(resource); err == nil {
+		profile = groupTrait.GetProfile().AsMap()
+	}
+
+	plan := strings.ToLower(profileString(profile, "type"))
+	if plan == "" {
+		plan = "corporate"
+	}
+	if plan != "corporate" && plan != "team" {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "expensify-connector: policies of type %q can't be created, use corporate or team", plan)
+	}
+
+	created, rlData, err := o.client.CreatePolicy(ctx, name, plan)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to create policy: %w", err)
+	}
+
+	if currency := strings.ToUpper(profileString(profile, "output_currency")); currency != "" {
+		rlData, err = o.client.UpdatePolicies(ctx, []string{created.ID}, expensify.PolicyUpdate{OutputCurrency: currency})
+		if rlData != nil {
+			annos = annotationsWithRateLimit(rlData)
+		}
+		if err != nil {
+			return nil, annos, fmt.Errorf("expensify-connector: policy %s was created but setting its output currency failed: %w", created.ID, err)
+		}
+	}
+
+	policy, rlData, err := o.client.GetPolicy(ctx, created.ID)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: policy %s was created but fetching it failed: %w", created.ID, err)
+	}
+
+	var admins []expensify.EmployeeUpdate
+	for _, email := range profileStringList(profile, "admins") {
+		if strings.EqualFold(email, policy.Owner) {
+			continue
+		}
+		admins = append(admins, expensify.EmployeeUpdate{
+			EmployeeEmail: email,
+			ManagerEmail:  policy.Owner,
+			PolicyID:      policy.ID,
+			Role:          "admin",
+		})
+	}
+	if len(admins) > 0 {
+		rlData, err = o.client.UpdateEmployees(ctx, admins)
+		if rlData != nil {
+			annos = annotationsWithRateLimit(rlData)
+		}
+		if err != nil {
+			return nil, annos, fmt.Errorf("expensify-connector: policy %s was created but inviting its admins failed: %w", policy.ID, err)
+		}
+	}
+
+	pr, err := policyResource(ctx, *policy)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return pr, annos, nil
+}
+
+// Delete is required alongside Create, but the Integration Server has no job for deleting policies.
+func (o *policyResourceType) Delete(_ context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	return nil, status.Errorf(codes.Unimplemented, "expensify-connector: policy %s can't be deleted, Expensify doesn't support deleting policies through its API", resourceId.GetResource())
 }
@@ -2,18 +2,60 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/conductorone/baton-expensify/pkg/expensify"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// approvalRelationship is an approval chain field of the employee record, modeled as an entitlement on
+// the employee that is granted to whoever the field points at.
+type approvalRelationship struct {
+	slug        string
+	displayName string
+	description string
+	approver    func(user *expensify.User) string
+}
+
+var approvalRelationships = []approvalRelationship{
+	{
+		slug:        "approver",
+		displayName: "Approves reports of %s",
+		description: "Approves the expense reports %s submits",
+		approver:    func(user *expensify.User) string { return user.SubmitsTo },
+	},
+	{
+		slug:        "forwards_to",
+		displayName: "Receives reports forwarded by %s",
+		description: "Receives the expense reports %s approves, as the next approver",
+		approver:    func(user *expensify.User) string { return user.ForwardsTo },
+	},
+	{
+		slug:        "over_limit_approver",
+		displayName: "Approves over-limit reports of %s",
+		description: "Approves the expense reports %s approves that exceed their approval limit",
+		approver:    func(user *expensify.User) string { return user.OverLimitForwardsTo },
+	},
+}
+
 type userResourceType struct {
 	resourceType *v2.ResourceType
 	client       *expensify.Client
+	employees    *employeeCache
+	// pageSize is how many policies a page of users walks through.
+	pageSize int
 }
 
 func (o *userResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -21,25 +63,52 @@ func (o *userResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 }
 
 // Create a new connector resource for Expensify employee.
-func userResource(ctx context.Context, user *expensify.User, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+func userResource(ctx context.Context, user *expensify.User) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"login":   user.Email,
-		"user_id": user.Email,
+		"login":         user.Email,
+		"user_id":       user.Email,
+		"is_terminated": user.IsTerminated,
+		"pending":       user.Pending,
+	}
+	setProfileValue(profile, "first_name", user.FirstName)
+	setProfileValue(profile, "last_name", user.LastName)
+	setProfileValue(profile, "employee_id", user.EmployeeID)
+	setProfileValue(profile, "submits_to", user.SubmitsTo)
+	setProfileValue(profile, "forwards_to", user.ForwardsTo)
+	setProfileValue(profile, "over_limit_forwards_to", user.OverLimitForwardsTo)
+	setProfileValue(profile, "custom_field_1", user.CustomField1)
+	setProfileValue(profile, "custom_field_2", user.CustomField2)
+	if user.ApprovalLimit != 0 {
+		profile["approval_limit"] = user.ApprovalLimit
 	}
 
 	userTraitOptions := []rs.UserTraitOption{
 		rs.WithUserProfile(profile),
 		rs.WithEmail(user.Email, true),
-		rs.WithStatus(v2.UserTrait_Status_STATUS_ENABLED),
+		rs.WithUserLogin(user.Email),
+		rs.WithDetailedStatus(userStatus(user)),
+	}
+	if user.EmployeeID != "" {
+		userTraitOptions = append(userTraitOptions, rs.WithEmployeeID(user.EmployeeID))
+	}
+	if user.FirstName != "" || user.LastName != "" {
+		userTraitOptions = append(userTraitOptions, rs.WithStructuredName(&v2.UserTrait_StructuredName{
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+		}))
+	}
+
+	displayName := user.FullName()
+	if displayName == "" {
+		displayName = user.Email
 	}
 
 	ret, err := rs.NewUserResource(
-		user.Email,
+		displayName,
 		resourceTypeUser,
 		// there is no userId in response
-		user.Email,
+		userResourceID(user.Email),
 		userTraitOptions,
-		rs.WithParentResourceID(parentResourceID),
 	)
 	if err != nil {
 		return nil, err
@@ -48,40 +117,618 @@ func userResource(ctx context.Context, user *expensify.User, parentResourceID *v
 	return ret, nil
 }
 
-func (o *userResourceType) List(ctx context.Context, parentId *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	if parentId == nil {
-		return nil, "", nil, nil
+// userStatus derives the trait status from the employee record. Terminated employees are disabled, and
+// pending invitations stay enabled but say so in the details.
+func userStatus(user *expensify.User) (v2.UserTrait_Status_Status, string) {
+	switch {
+	case user.IsTerminated:
+		return v2.UserTrait_Status_STATUS_DISABLED, "terminated"
+	case user.Pending:
+		return v2.UserTrait_Status_STATUS_ENABLED, "invitation pending"
+	default:
+		return v2.UserTrait_Status_STATUS_ENABLED, ""
+	}
+}
+
+// userStatusRank orders employee records from most to least active.
+func userStatusRank(user *expensify.User) int {
+	switch {
+	case user.IsTerminated:
+		return 2
+	case user.Pending:
+		return 1
+	default:
+		return 0
 	}
+}
 
-	users, err := o.client.GetPolicyEmployees(ctx, parentId.Resource)
+// List walks the policies in chunks of pageSize, listing the employees they hold, and finishes with the
+// policy owners and domain members who aren't employees of any policy.
+func (o *userResourceType) List(ctx context.Context, _ *v2.ResourceId, token *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if token == nil || token.Token == "" {
+		o.employees.listStarted()
+	}
+
+	bag, lastPolicyID, err := parsePageToken(token, resourceTypePolicy.Id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	policies, rlData, err := o.employees.policies(ctx)
+	annos := annotationsWithRateLimit(rlData)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("expensify-connector: failed to list users: %w", err)
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list policies: %w", err)
+	}
+
+	if bag.ResourceTypeID() == resourceTypePolicy.Id {
+		start := pageStart(policies, lastPolicyID)
+		end := min(start+o.pageSize, len(policies))
+		rv, rlData, err := o.listEmployees(ctx, policies, start, end)
+		if rlData != nil {
+			annos = annotationsWithRateLimit(rlData)
+		}
+		if err != nil {
+			return nil, "", annos, err
+		}
+
+		if end < len(policies) {
+			nextToken, err := bag.NextToken(policies[end-1].ID)
+			if err != nil {
+				return nil, "", annos, err
+			}
+			return rv, nextToken, annos, nil
+		}
+
+		bag.Pop()
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeDomain.Id})
+		nextToken, err := bag.Marshal()
+		if err != nil {
+			return nil, "", annos, err
+		}
+		return rv, nextToken, annos, nil
+	}
+
+	seen := make(map[string]struct{})
+	for _, policy := range policies {
+		users, rlData, err := o.employees.policyEmployees(ctx, policy.ID)
+		if rlData != nil {
+			annos = annotationsWithRateLimit(rlData)
+		}
+		if err != nil {
+			return nil, "", annos, fmt.Errorf("expensify-connector: failed to list users: %w", err)
+		}
+		for _, user := range users {
+			seen[userResourceID(user.Email)] = struct{}{}
+		}
 	}
 
 	var rv []*v2.Resource
-	for _, user := range users {
-		userCopy := user
-		ur, err := userResource(ctx, &userCopy, parentId)
+	// An owner need not be listed as an employee of their own policy.
+	for _, policy := range policies {
+		if _, ok := seen[userResourceID(policy.Owner)]; ok || policy.Owner == "" {
+			continue
+		}
+		seen[userResourceID(policy.Owner)] = struct{}{}
+
+		ur, err := userResource(ctx, &expensify.User{Email: policy.Owner})
 		if err != nil {
 			return nil, "", nil, err
 		}
 		rv = append(rv, ur)
 	}
 
-	return rv, "", nil, nil
+	// Domain members, and domain admins in particular, need not belong to any policy.
+	domains, rlData, err := o.employees.domains(ctx)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domains: %w", err)
+	}
+
+	for _, domain := range domains {
+		members, rlData, err := o.employees.domainMembers(ctx, domain.Name)
+		if rlData != nil {
+			annos = annotationsWithRateLimit(rlData)
+		}
+		if err != nil {
+			return nil, "", annos, fmt.Errorf("expensify-connector: failed to list domain members: %w", err)
+		}
+
+		for _, member := range members {
+			if _, ok := seen[userResourceID(member.Email)]; ok {
+				continue
+			}
+			seen[userResourceID(member.Email)] = struct{}{}
+
+			ur, err := userResource(ctx, &expensify.User{
+				Email:     member.Email,
+				FirstName: member.FirstName,
+				LastName:  member.LastName,
+			})
+			if err != nil {
+				return nil, "", nil, err
+			}
+			rv = append(rv, ur)
+		}
+	}
+
+	return rv, "", annos, nil
 }
 
-func (o *userResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+// listEmployees lists the employees that belong to the page of policies[start:end]. The syncer keeps
+// the first record it sees of a resource, so each user is listed once: on the page of the first policy
+// they are active in, or, if they aren't active in any policy, on the last page with their most active
+// record. Only policies[:end] are read, so a page never waits on the policies after it, but a page
+// resumed without a warm cache reads the employees of the earlier pages again to tell who was listed.
+func (o *userResourceType) listEmployees(
+	ctx context.Context,
+	policies []expensify.Policy,
+	start int,
+	end int,
+) ([]*v2.Resource, *v2.RateLimitDescription, error) {
+	var (
+		rlData  *v2.RateLimitDescription
+		records []expensify.User
+		// active is the index of the first policy each user is active in, or -1.
+		active []int
+	)
+	index := make(map[string]int)
+	for i, policy := range policies[:end] {
+		users, policyRlData, err := o.employees.policyEmployees(ctx, policy.ID)
+		if policyRlData != nil {
+			rlData = policyRlData
+		}
+		if err != nil {
+			return nil, rlData, fmt.Errorf("expensify-connector: failed to list users: %w", err)
+		}
+
+		for _, user := range users {
+			j, ok := index[userResourceID(user.Email)]
+			if !ok {
+				j = len(records)
+				index[userResourceID(user.Email)] = j
+				records = append(records, user)
+				active = append(active, -1)
+			} else if userStatusRank(&user) < userStatusRank(&records[j]) {
+				// Keep the most active record, so a user is only disabled once they're terminated everywhere.
+				records[j] = user
+			}
+			if active[j] < 0 && userStatusRank(&user) == 0 {
+				active[j] = i
+			}
+		}
+	}
+
+	var rv []*v2.Resource
+	for j := range records {
+		if active[j] >= 0 && (active[j] < start || active[j] >= end) {
+			continue
+		}
+		if active[j] < 0 && end < len(policies) {
+			continue
+		}
+		ur, err := userResource(ctx, &records[j])
+		if err != nil {
+			return nil, rlData, err
+		}
+		rv = append(rv, ur)
+	}
+
+	return rv, rlData, nil
 }
 
-func (o *userResourceType) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+// Entitlements returns the approval relationships other users can hold over this user.
+func (o *userResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	rv := make([]*v2.Entitlement, 0, len(approvalRelationships))
+	for _, relationship := range approvalRelationships {
+		rv = append(rv, ent.NewPermissionEntitlement(
+			resource,
+			relationship.slug,
+			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithDisplayName(fmt.Sprintf(relationship.displayName, resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf(relationship.description, resource.DisplayName)),
+		))
+	}
+
+	return rv, "", nil, nil
 }
 
-func userBuilder(client *expensify.Client) *userResourceType {
+// Grants returns a grant to each user that approves this user's reports, in any of their policies.
+func (o *userResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	o.employees.grantsStarted()
+	records, rlData, err := o.employees.userRecords(ctx, resource.Id.Resource)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("expensify-connector: failed to get employee records: %w", err)
+	}
+
+	var rv []*v2.Grant
+	seen := make(map[string]struct{})
+	for _, record := range records {
+		for _, relationship := range approvalRelationships {
+			approver := relationship.approver(&record.User)
+			if approver == "" || strings.EqualFold(approver, record.Email) {
+				continue
+			}
+
+			key := relationship.slug + ":" + strings.ToLower(approver)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			approverID, err := rs.NewResourceID(resourceTypeUser, userResourceID(approver))
+			if err != nil {
+				return nil, "", annos, err
+			}
+			rv = append(rv, grant.NewGrant(resource, relationship.slug, approverID))
+		}
+	}
+
+	return rv, "", annos, nil
+}
+
+func userBuilder(client *expensify.Client, employees *employeeCache, pageSize int) *userResourceType {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
 	return &userResourceType{
 		resourceType: resourceTypeUser,
 		client:       client,
+		employees:    employees,
+		pageSize:     pageSize,
+	}
+}
+
+func (o *userResourceType) CreateAccountCapabilityDetails(_ context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_SSO,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+// CreateAccount invites the email into every target policy as a user. Policies the email already belongs
+// to are left alone, so an existing admin or auditor is never demoted. Expensify sends the invitation
+// itself, so no credentials are ever returned.
+func (o *userResourceType) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	_ *v2.CredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	profile := accountInfo.GetProfile().AsMap()
+
+	email := profileString(profile, "email")
+	if email == "" && len(accountInfo.GetEmails()) > 0 {
+		email = accountInfo.GetEmails()[0].GetAddress()
 	}
+	if email == "" {
+		email = accountInfo.GetLogin()
+	}
+	if email == "" {
+		return nil, nil, nil, fmt.Errorf("expensify-connector: email is required to create an account")
+	}
+
+	policyIDs := profileStringList(profile, "policy_ids")
+	if len(policyIDs) == 0 {
+		return nil, nil, nil, fmt.Errorf("expensify-connector: at least one policy id is required to create an account")
+	}
+
+	user := expensify.User{
+		Email:      email,
+		Role:       "user",
+		FirstName:  profileString(profile, "first_name"),
+		LastName:   profileString(profile, "last_name"),
+		EmployeeID: profileString(profile, "employee_id"),
+		SubmitsTo:  profileString(profile, "approver_email"),
+	}
+
+	policies := make(map[string]expensify.Policy, len(policyIDs))
+	for _, policyID := range policyIDs {
+		policy, err := o.employees.policy(ctx, policyID)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("expensify-connector: failed to get policy: %w", err)
+		}
+		if err := writablePolicy(policy); err != nil {
+			return nil, nil, nil, err
+		}
+		policies[policyID] = policy
+	}
+
+	employees, rlData, err := o.client.GetPoliciesEmployees(ctx, policyIDs)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, nil, annos, fmt.Errorf("expensify-connector: failed to get policy employees: %w", err)
+	}
+
+	var (
+		updates  []expensify.EmployeeUpdate
+		existing *expensify.User
+	)
+	for _, policyID := range policyIDs {
+		if employee, ok := findEmployee(employees[policyID], email); ok {
+			if existing == nil || userStatusRank(&employee) < userStatusRank(existing) {
+				existing = &employee
+			}
+			continue
+		}
+
+		managerEmail := user.SubmitsTo
+		if managerEmail == "" {
+			managerEmail = policies[policyID].Owner
+		}
+
+		updates = append(updates, expensify.EmployeeUpdate{
+			EmployeeEmail: user.Email,
+			ManagerEmail:  managerEmail,
+			PolicyID:      policyID,
+			Role:          user.Role,
+			EmployeeID:    user.EmployeeID,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+		})
+	}
+
+	// An invitation whose response was lost leaves nothing to do on retry, which is still a success.
+	if len(updates) == 0 {
+		ur, err := userResource(ctx, existing)
+		if err != nil {
+			return nil, nil, annos, err
+		}
+		return &v2.CreateAccountResponse_SuccessResult{
+			Resource: ur,
+		}, nil, annos, nil
+	}
+
+	rlData, err = o.client.UpdateEmployees(ctx, updates)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, nil, annos, fmt.Errorf("expensify-connector: failed to invite employee: %w", err)
+	}
+
+	ur, err := userResource(ctx, &user)
+	if err != nil {
+		return nil, nil, annos, err
+	}
+
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource: ur,
+	}, nil, annos, nil
+}
+
+// Delete removes the user from every policy the credentials administer. Each policy is attempted even
+// if an earlier one fails, and the outcome for every policy is reported in the annotations.
+func (o *userResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("expensify-connector: cannot delete resource of type %s", resourceId.ResourceType)
+	}
+	email := resourceId.Resource
+
+	results, rlData, err := terminateEmployee(ctx, o.employees, email, nil)
+	annos := annotationsWithRateLimit(rlData)
+	if results == nil && err != nil {
+		return annos, err
+	}
+
+	summary, summaryErr := structpb.NewStruct(map[string]interface{}{
+		"email":           email,
+		"policy_removals": results,
+	})
+	if summaryErr != nil {
+		return annos, summaryErr
+	}
+	annos.Append(summary)
+
+	return annos, err
+}
+
+// terminateEmployee removes the employee from the given policies, or from every policy the credentials
+// administer if none are given. The employees of the policies are fetched in batches, each policy is
+// attempted even if an earlier one fails, and the outcome for every policy the employee belonged to is
+// returned. The policies the employee was removed from are refreshed in the cache.
+func terminateEmployee(
+	ctx context.Context,
+	cache *employeeCache,
+	email string,
+	policyIDs []string,
+) ([]interface{}, *v2.RateLimitDescription, error) {
+	policies, rlData, err := cache.client.GetPolicies(ctx)
+	if err != nil {
+		return nil, rlData, fmt.Errorf("expensify-connector: failed to list policies: %w", err)
+	}
+
+	var (
+		results = []interface{}{}
+		errs    []error
+		targets []expensify.Policy
+	)
+	for _, policy := range policies {
+		if len(policyIDs) == 0 && policyReadOnly(policy) {
+			continue
+		}
+		if len(policyIDs) > 0 && !slices.Contains(policyIDs, policy.ID) {
+			continue
+		}
+
+		if policyReadOnly(policy) {
+			results = append(results, map[string]interface{}{
+				"policy_id":   policy.ID,
+				"policy_name": policy.Name,
+				"status":      "failed",
+				"error":       "policy is read-only",
+			})
+			errs = append(errs, fmt.Errorf("policy %s: read-only, the partner user is only its %s", policy.ID, policy.Role))
+			continue
+		}
+		targets = append(targets, policy)
+	}
+
+	// A policy that was asked for but isn't listed is mistyped or not accessible to the credentials.
+	var unknown []string
+	for _, policyID := range policyIDs {
+		if slices.ContainsFunc(policies, func(policy expensify.Policy) bool { return policy.ID == policyID }) {
+			continue
+		}
+		unknown = append(unknown, policyID)
+		results = append(results, map[string]interface{}{
+			"policy_id": policyID,
+			"status":    "failed",
+			"error":     "policy not found",
+		})
+	}
+
+	targetIDs := make([]string, 0, len(targets))
+	for _, policy := range targets {
+		targetIDs = append(targetIDs, policy.ID)
+	}
+	employees, employeesRlData, err := fetchEmployees(ctx, cache.client, targetIDs, cache.batchSize, cache.concurrency)
+	if employeesRlData != nil {
+		rlData = employeesRlData
+	}
+	if err != nil {
+		return nil, rlData, fmt.Errorf("expensify-connector: failed to get policy employees: %w", err)
+	}
+
+	for _, policy := range targets {
+		if _, ok := findEmployee(employees[policy.ID], email); !ok {
+			continue
+		}
+
+		result := map[string]interface{}{
+			"policy_id":   policy.ID,
+			"policy_name": policy.Name,
+		}
+
+		removeRlData, err := cache.client.RemoveEmployee(ctx, policy.ID, email)
+		if removeRlData != nil {
+			rlData = removeRlData
+		}
+		cache.invalidate(policy.ID)
+		if err != nil {
+			result["status"] = "failed"
+			result["error"] = err.Error()
+			errs = append(errs, fmt.Errorf("policy %s: %w", policy.ID, err))
+		} else {
+			result["status"] = "removed"
+		}
+		results = append(results, result)
+	}
+
+	if len(unknown) > 0 {
+		msg := fmt.Sprintf("expensify-connector: policies not found: %s", strings.Join(unknown, ", "))
+		if len(errs) > 0 {
+			msg += fmt.Sprintf(", and failed to remove user from %d policies: %v", len(errs), errors.Join(errs...))
+		}
+		return results, rlData, status.Error(codes.NotFound, msg)
+	}
+	if len(errs) > 0 {
+		return results, rlData, fmt.Errorf("expensify-connector: failed to remove user from %d policies: %w", len(errs), errors.Join(errs...))
+	}
+
+	return results, rlData, nil
+}
+
+// Get refreshes a single user by looking them up across every accessible policy, then as a policy
+// owner and finally as a domain member, bypassing the sync cache. The policies the user belongs to are
+// refreshed in the cache, since a targeted sync reads the user's grants right after Get.
+func (o *userResourceType) Get(ctx context.Context, resourceId *v2.ResourceId, _ *v2.ResourceId) (*v2.Resource, annotations.Annotations, error) {
+	email := resourceId.Resource
+
+	policies, rlData, err := o.client.GetPolicies(ctx)
+	annos := annotationsWithRateLimit(rlData)
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to list policies: %w", err)
+	}
+	policies = o.employees.filter.apply(policies)
+
+	user, found, rlData, err := o.lookupEmployee(ctx, policies, email)
+	if rlData != nil {
+		annos = annotationsWithRateLimit(rlData)
+	}
+	if err != nil {
+		return nil, annos, fmt.Errorf("expensify-connector: failed to get policy employees: %w", err)
+	}
+
+	if !found {
+		for _, policy := range policies {
+			if strings.EqualFold(policy.Owner, email) {
+				user, found = expensify.User{Email: policy.Owner}, true
+				break
+			}
+		}
+	}
+
+	if !found {
+		domains, rlData, err := o.client.GetDomains(ctx)
+		if rlData != nil {
+			annos = annotationsWithRateLimit(rlData)
+		}
+		if err != nil && !domainsUnavailable(err) {
+			return nil, annos, fmt.Errorf("expensify-connector: failed to list domains: %w", err)
+		}
+
+		for _, domain := range domains {
+			members, _, err := o.client.GetDomainMembers(ctx, domain.Name)
+			if err != nil {
+				return nil, annos, fmt.Errorf("expensify-connector: failed to list domain members: %w", err)
+			}
+			for _, member := range members {
+				if strings.EqualFold(member.Email, email) {
+					user, found = expensify.User{Email: member.Email, FirstName: member.FirstName, LastName: member.LastName}, true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+	}
+
+	if !found {
+		return nil, annos, status.Errorf(codes.NotFound, "expensify-connector: user %s not found", email)
+	}
+
+	ur, err := userResource(ctx, &user)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return ur, annos, nil
+}
+
+// lookupEmployee finds the most active employee record of the user across the policies.
+func (o *userResourceType) lookupEmployee(
+	ctx context.Context,
+	policies []expensify.Policy,
+	email string,
+) (expensify.User, bool, *v2.RateLimitDescription, error) {
+	policyIDs := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		policyIDs = append(policyIDs, policy.ID)
+	}
+
+	employees, rlData, err := fetchEmployees(ctx, o.client, policyIDs, o.employees.batchSize, o.employees.concurrency)
+	if err != nil {
+		return expensify.User{}, false, rlData, err
+	}
+	o.employees.refreshUser(email, employees)
+
+	var (
+		user  expensify.User
+		found bool
+	)
+	for _, policyID := range policyIDs {
+		employee, ok := findEmployee(employees[policyID], email)
+		if ok && (!found || userStatusRank(&employee) < userStatusRank(&user)) {
+			user, found = employee, true
+		}
+	}
+
+	return user, found, rlData, nil
 }
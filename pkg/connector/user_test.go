@@ -0,0 +1,194 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-expensify/pkg/expensify"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+// fakeExpensify serves the policy list and policy employees from memory, standing in for the
+// Integration Server.
+type fakeExpensify struct {
+	mtx       sync.Mutex
+	policies  []expensify.Policy
+	employees map[string][]expensify.User
+}
+
+func (f *fakeExpensify) setEmployees(policyID string, employees ...expensify.User) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.employees[policyID] = employees
+}
+
+func (f *fakeExpensify) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var job struct {
+		InputSettings struct {
+			Type         string   `json:"type"`
+			PolicyIDList []string `json:"policyIDList"`
+		} `json:"inputSettings"`
+	}
+	if err := json.Unmarshal([]byte(r.FormValue("requestJobDescription")), &job); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	rv := map[string]interface{}{"responseCode": http.StatusOK}
+	switch job.InputSettings.Type {
+	case "policyList":
+		rv["policyList"] = f.policies
+	case "policy":
+		info := make(map[string]expensify.Employees)
+		for _, policyID := range job.InputSettings.PolicyIDList {
+			info[policyID] = expensify.Employees{Employees: f.employees[policyID]}
+		}
+		rv["policyInfo"] = info
+	case "domainList":
+		rv["domainList"] = []expensify.Domain{}
+	default:
+		rv["responseCode"] = http.StatusNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rv)
+}
+
+func newFakeClient(t *testing.T, fake *fakeExpensify) *expensify.Client {
+	t.Helper()
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := expensify.NewClient(context.Background(), "partner", "secret", expensify.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func TestListEmployeesOncePerUser(t *testing.T) {
+	fake := &fakeExpensify{
+		policies: []expensify.Policy{
+			{ID: "P04", Name: "Policy 4", Owner: "owner@x.com", Role: "admin"},
+			{ID: "P01", Name: "Policy 1", Owner: "owner@x.com", Role: "admin"},
+			{ID: "P03", Name: "Policy 3", Owner: "owner@x.com", Role: "admin"},
+			{ID: "P02", Name: "Policy 2", Owner: "owner@x.com", Role: "admin"},
+			{ID: "P05", Name: "Policy 5", Owner: "other@x.com", Role: "admin"},
+		},
+		employees: map[string][]expensify.User{
+			"P01": {
+				{Email: "alice@x.com", Role: "user"},
+				{Email: "shared@x.com", Role: "user", IsTerminated: true},
+				{Email: "gone@x.com", Role: "user", IsTerminated: true},
+			},
+			"P02": {
+				{Email: "bob@x.com", Role: "user"},
+				{Email: "Alice@X.com", Role: "admin"},
+				{Email: "invited@x.com", Role: "user", Pending: true},
+			},
+			"P03": {
+				{Email: "shared@x.com", Role: "auditor"},
+				{Email: "Frank@x.com", Role: "user", Pending: true},
+			},
+			"P04": {
+				{Email: "frank@x.com", Role: "user"},
+				{Email: "gone@x.com", Role: "user", IsTerminated: true},
+			},
+			"P05": {
+				{Email: "owner@x.com", Role: "admin"},
+			},
+		},
+	}
+	client := newFakeClient(t, fake)
+
+	want := map[string]v2.UserTrait_Status_Status{
+		"alice@x.com":   v2.UserTrait_Status_STATUS_ENABLED,
+		"bob@x.com":     v2.UserTrait_Status_STATUS_ENABLED,
+		"shared@x.com":  v2.UserTrait_Status_STATUS_ENABLED,
+		"frank@x.com":   v2.UserTrait_Status_STATUS_ENABLED,
+		"invited@x.com": v2.UserTrait_Status_STATUS_ENABLED,
+		"gone@x.com":    v2.UserTrait_Status_STATUS_DISABLED,
+		"owner@x.com":   v2.UserTrait_Status_STATUS_ENABLED,
+		"other@x.com":   v2.UserTrait_Status_STATUS_ENABLED,
+	}
+
+	tests := []struct {
+		name     string
+		pageSize int
+		// resume builds a new cache for every page, as a sync resumed from a checkpoint would.
+		resume bool
+	}{
+		{"one policy per page", 1, false},
+		{"two policies per page", 2, false},
+		{"every policy on one page", 100, false},
+		{"resumed one policy per page", 1, true},
+		{"resumed three policies per page", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			o := userBuilder(client, newEmployeeCache(client, 2, 2, nil), tt.pageSize)
+
+			seen := make(map[string]int)
+			token := &pagination.Token{}
+			for page := 0; ; page++ {
+				if page > 20 {
+					t.Fatal("List did not finish")
+				}
+				if tt.resume {
+					o = userBuilder(client, newEmployeeCache(client, 2, 2, nil), tt.pageSize)
+				}
+
+				resources, nextToken, _, err := o.List(ctx, nil, token)
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				for _, resource := range resources {
+					id := resource.Id.Resource
+					seen[id]++
+					if seen[id] > 1 {
+						t.Errorf("user %q listed more than once", id)
+						continue
+					}
+
+					status := v2.UserTrait_Status_STATUS_UNSPECIFIED
+					userTrait := &v2.UserTrait{}
+					for _, annotation := range resource.Annotations {
+						if annotation.UnmarshalTo(userTrait) == nil {
+							status = userTrait.GetStatus().GetStatus()
+						}
+					}
+					if status != want[id] {
+						t.Errorf("user %q has status %v, want %v", id, status, want[id])
+					}
+				}
+
+				if nextToken == "" {
+					break
+				}
+				token = &pagination.Token{Token: nextToken}
+			}
+
+			for email := range want {
+				if seen[email] == 0 {
+					t.Errorf("user %q was not listed", email)
+				}
+			}
+			for id := range seen {
+				if _, ok := want[id]; !ok {
+					t.Errorf("unexpected user %q listed", id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package expensify
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// Card is a company card assigned on a domain.
+type Card struct {
+	CardID     int64  `json:"cardID"`
+	CardName   string `json:"cardName"`
+	CardNumber string `json:"cardNumber"`
+	// Bank is the card feed the card is imported from.
+	Bank  string `json:"bank"`
+	Email string `json:"email"`
+	// Limit is in cents, and zero when the feed doesn't report one.
+	Limit      int64  `json:"limit"`
+	LastImport string `json:"lastImport"`
+}
+
+// LastFour returns the last four digits of the masked card number.
+func (c *Card) LastFour() string {
+	if len(c.CardNumber) <= 4 {
+		return c.CardNumber
+	}
+	return c.CardNumber[len(c.CardNumber)-4:]
+}
+
+type DomainCardListInputSettings struct {
+	Type   string `json:"type"`
+	Domain string `json:"domain"`
+}
+
+type DomainCardListRequestBody struct {
+	Type          string                      `json:"type"`
+	Credentials   Credentials                 `json:"credentials"`
+	InputSettings DomainCardListInputSettings `json:"inputSettings"`
+}
+
+func (b DomainCardListRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+type DomainCardListResponse struct {
+	CardList     []Card `json:"cardList"`
+	ResponseCode int64  `json:"responseCode"`
+}
+
+// GetDomainCards returns the company cards assigned on the domain.
+func (c *Client) GetDomainCards(ctx context.Context, domain string) ([]Card, *v2.RateLimitDescription, error) {
+	body := DomainCardListRequestBody{
+		Type: "get",
+		Credentials: Credentials{
+			PartnerUserID:     c.partnerUserID,
+			PartnerUserSecret: c.partnerUserSecret,
+		},
+		InputSettings: DomainCardListInputSettings{
+			Type:   "domainCardList",
+			Domain: domain,
+		},
+	}
+
+	var res DomainCardListResponse
+	rlData, err := c.doRequest(ctx, body, &res)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return res.CardList, rlData, nil
+}
@@ -4,35 +4,60 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
 const BaseUrl = "https://integrations.expensify.com/Integration-Server/ExpensifyIntegrations"
 
 type Client struct {
 	httpClient        *uhttp.BaseHttpClient
+	baseURL           string
 	partnerUserID     string
 	partnerUserSecret string
+	maxRetries        int
+	proxyURL          string
+	caBundlePath      string
+	requestTimeout    time.Duration
+	adminOnly         bool
+
+	// throttle is shared by every request, so concurrent callers back off together.
+	throttle throttle
 }
 
-func NewClient(ctx context.Context, partnerUserID string, partnerUserSecret string) (*Client, error) {
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+func NewClient(ctx context.Context, partnerUserID string, partnerUserSecret string, opts ...Option) (*Client, error) {
+	c := &Client{
+		baseURL:           BaseUrl,
+		partnerUserID:     partnerUserID,
+		partnerUserSecret: partnerUserSecret,
+		maxRetries:        DefaultMaxRetries,
+		adminOnly:         true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if _, err := url.ParseRequestURI(c.baseURL); err != nil {
+		return nil, fmt.Errorf("invalid base url %q: %w", c.baseURL, err)
+	}
+
+	httpClient, err := c.newHTTPClient(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create http client: %w", err)
 	}
+	c.httpClient = uhttp.NewBaseHttpClient(httpClient)
 
-	return &Client{
-		partnerUserID:     partnerUserID,
-		partnerUserSecret: partnerUserSecret,
-		httpClient:        uhttp.NewBaseHttpClient(httpClient),
-	}, nil
+	return c, nil
 }
 
 type Credentials struct {
@@ -75,13 +100,22 @@ type PolicyResponse struct {
 	ResponseCode int64                `json:"responseCode"`
 }
 
-type Error struct {
-	Message    string `json:"responseMessage"`
-	StatusCode int    `json:"responseCode"`
+// requestJob is a request body that can describe its own job type for error reporting.
+type requestJob interface {
+	jobType() string
 }
 
-// GetPolicies returns policies that user is an admin of.
-func (c *Client) GetPolicies(ctx context.Context) ([]Policy, error) {
+func (b PolicyRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+func (b PoliciesRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+// GetPolicies returns the policies the user is an admin of, or every policy they belong to if the
+// client was created WithNonAdminPolicies. Policy.Role holds the user's own role in each.
+func (c *Client) GetPolicies(ctx context.Context) ([]Policy, *v2.RateLimitDescription, error) {
 	body := PoliciesRequestBody{
 		Type: "get",
 		Credentials: Credentials{
@@ -90,24 +124,52 @@ func (c *Client) GetPolicies(ctx context.Context) ([]Policy, error) {
 		},
 		InputSettings: PoliciesInputSettings{
 			Type:      "policyList",
-			AdminOnly: true,
+			AdminOnly: c.adminOnly,
 		},
 	}
 
 	var res PolicyListResponse
-	err := c.doRequest(ctx, body, &res)
+	rlData, err := c.doRequest(ctx, body, &res)
 	if err != nil {
-		return nil, err
+		return nil, rlData, err
 	}
 
-	return res.PolicyList, nil
+	return res.PolicyList, rlData, nil
 }
 
 // GetPolicyEmployees returns employees for a single policy.
-func (c *Client) GetPolicyEmployees(ctx context.Context, policyId string) ([]User, error) {
-	var fields, policyIDs []string
-	fields = append(fields, "employees")
-	policyIDs = append(policyIDs, policyId)
+func (c *Client) GetPolicyEmployees(ctx context.Context, policyId string) ([]User, *v2.RateLimitDescription, error) {
+	employees, rlData, err := c.GetPoliciesEmployees(ctx, []string{policyId})
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return employees[policyId], rlData, nil
+}
+
+// GetPolicy returns a single policy by ID. The policy getter job only returns data sections such as
+// employees, so the policy is looked up in the policy list, which carries its owner, type and role.
+func (c *Client) GetPolicy(ctx context.Context, policyID string) (*Policy, *v2.RateLimitDescription, error) {
+	policies, rlData, err := c.GetPolicies(ctx)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	for _, policy := range policies {
+		if policy.ID == policyID {
+			return &policy, rlData, nil
+		}
+	}
+
+	return nil, rlData, &Error{
+		ResponseCode:    http.StatusNotFound,
+		ResponseMessage: fmt.Sprintf("policy %s not found", policyID),
+		JobType:         "get/policyList",
+	}
+}
+
+// GetPoliciesEmployees returns employees for several policies in a single request, keyed by policy ID.
+func (c *Client) GetPoliciesEmployees(ctx context.Context, policyIDs []string) (map[string][]User, *v2.RateLimitDescription, error) {
 	body := PolicyRequestBody{
 		Type: "get",
 		Credentials: Credentials{
@@ -116,40 +178,89 @@ func (c *Client) GetPolicyEmployees(ctx context.Context, policyId string) ([]Use
 		},
 		InputSettings: PolicyInputSettings{
 			Type:         "policy",
-			Fields:       fields,
+			Fields:       []string{"employees"},
 			PolicyIDList: policyIDs,
 		},
 	}
 
 	var res PolicyResponse
-	err := c.doRequest(ctx, body, &res)
+	rlData, err := c.doRequest(ctx, body, &res)
 	if err != nil {
-		return nil, err
+		return nil, rlData, err
 	}
 
-	return res.PolicyInfo[policyId].Employees, nil
+	employees := make(map[string][]User, len(policyIDs))
+	for _, policyID := range policyIDs {
+		employees[policyID] = res.PolicyInfo[policyID].Employees
+	}
+
+	return employees, rlData, nil
 }
 
-func (c *Client) doRequest(ctx context.Context, body interface{}, resType interface{}) error {
+func (c *Client) doRequest(ctx context.Context, body requestJob, resType interface{}) (*v2.RateLimitDescription, error) {
+	return c.doRequestWithData(ctx, body, nil, resType)
+}
+
+// doRequestWithData sends the job, along with the JSON encoded payload for jobs that take input data,
+// and decodes the response into resType. It retries with backoff while the Integration Server reports
+// throttling and the retry budget allows it.
+func (c *Client) doRequestWithData(ctx context.Context, body requestJob, payload interface{}, resType interface{}) (*v2.RateLimitDescription, error) {
 	strBody, err := json.Marshal(body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	data := url.Values{}
 	data.Set("requestJobDescription", string(strBody))
+	if payload != nil {
+		strPayload, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		data.Set("data", string(strPayload))
+	}
+	form := data.Encode()
+
+	l := ctxzap.Extract(ctx)
+	var rlData *v2.RateLimitDescription
+	for attempt := 0; ; attempt++ {
+		if err := c.throttle.wait(ctx); err != nil {
+			return rlData, err
+		}
+
+		rlData, err = c.doRequestOnce(ctx, body, form, resType)
+		if err == nil || !errors.Is(err, ErrRateLimited) || attempt >= c.maxRetries {
+			return rlData, err
+		}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, BaseUrl, strings.NewReader(data.Encode()))
+		wait := backoff(attempt, rlData)
+		l.Warn("expensify: request throttled, backing off",
+			zap.String("job_type", body.jobType()),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("wait", wait),
+		)
+		c.throttle.until(time.Now().Add(wait))
+	}
+}
+
+func (c *Client) doRequestOnce(ctx context.Context, body requestJob, form string, resType interface{}) (*v2.RateLimitDescription, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL, strings.NewReader(form))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	resp, err := c.httpClient.Do(req)
+	rlData := &v2.RateLimitDescription{}
+	resp, err := c.httpClient.Do(req, uhttp.WithRatelimitData(rlData))
+	if resp != nil {
+		defer resp.Body.Close()
+	}
 	if err != nil {
-		return err
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			return rlData, fmt.Errorf("%w: %w", ErrRateLimited, err)
+		}
+		return rlData, err
 	}
-	defer resp.Body.Close()
 
 	var (
 		buf bytes.Buffer
@@ -158,14 +269,19 @@ func (c *Client) doRequest(ctx context.Context, body interface{}, resType interf
 
 	var errResp Error
 	if err = json.NewDecoder(r).Decode(&errResp); err != nil {
-		return err
-	} else if code := errResp.StatusCode; code != 0 && code != http.StatusOK {
-		return fmt.Errorf("error: %s", errResp.Message)
+		return rlData, err
+	} else if code := errResp.ResponseCode; code != 0 && code != http.StatusOK {
+		errResp.JobType = body.jobType()
+		if errors.Is(&errResp, ErrRateLimited) {
+			rlData.Status = v2.RateLimitDescription_STATUS_OVERLIMIT
+			rlData.Remaining = 0
+		}
+		return rlData, &errResp
 	}
 
 	if err := json.NewDecoder(&buf).Decode(&resType); err != nil {
-		return err
+		return rlData, err
 	}
 
-	return nil
+	return rlData, nil
 }
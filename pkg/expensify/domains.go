@@ -0,0 +1,184 @@
+package expensify
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// Domain is a verified company domain the credentials have access to.
+type Domain struct {
+	Name string `json:"domain"`
+}
+
+// DomainMember is an account on a verified domain.
+type DomainMember struct {
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	IsAdmin   bool   `json:"isAdmin"`
+}
+
+// DomainGroup is a group of domain members that shares default policy and card permissions.
+type DomainGroup struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	DefaultPolicyID string `json:"defaultPolicyID"`
+	// RestrictedPolicy reports whether members may only use the default policy.
+	RestrictedPolicy bool `json:"restrictedPolicy"`
+}
+
+type DomainInfo struct {
+	Members []DomainMember `json:"members"`
+	Groups  []DomainGroup  `json:"groups"`
+}
+
+type DomainsInputSettings struct {
+	Type string `json:"type"`
+}
+
+type DomainsRequestBody struct {
+	Type          string               `json:"type"`
+	Credentials   Credentials          `json:"credentials"`
+	InputSettings DomainsInputSettings `json:"inputSettings"`
+}
+
+func (b DomainsRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+type DomainInputSettings struct {
+	Type   string   `json:"type"`
+	Domain string   `json:"domain"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+type DomainGroupInputSettings struct {
+	Type    string `json:"type"`
+	Domain  string `json:"domain"`
+	GroupID string `json:"groupID"`
+}
+
+type DomainGroupRequestBody struct {
+	Type          string                   `json:"type"`
+	Credentials   Credentials              `json:"credentials"`
+	InputSettings DomainGroupInputSettings `json:"inputSettings"`
+}
+
+func (b DomainGroupRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+type DomainRequestBody struct {
+	Type          string              `json:"type"`
+	Credentials   Credentials         `json:"credentials"`
+	InputSettings DomainInputSettings `json:"inputSettings"`
+}
+
+func (b DomainRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+type DomainListResponse struct {
+	DomainList   []Domain `json:"domainList"`
+	ResponseCode int64    `json:"responseCode"`
+}
+
+type DomainGroupResponse struct {
+	Members      []DomainMember `json:"members"`
+	ResponseCode int64          `json:"responseCode"`
+}
+
+type DomainResponse struct {
+	DomainInfo   DomainInfo `json:"domainInfo"`
+	ResponseCode int64      `json:"responseCode"`
+}
+
+// GetDomains returns the verified domains the credentials are a domain admin of.
+func (c *Client) GetDomains(ctx context.Context) ([]Domain, *v2.RateLimitDescription, error) {
+	body := DomainsRequestBody{
+		Type: "get",
+		Credentials: Credentials{
+			PartnerUserID:     c.partnerUserID,
+			PartnerUserSecret: c.partnerUserSecret,
+		},
+		InputSettings: DomainsInputSettings{
+			Type: "domainList",
+		},
+	}
+
+	var res DomainListResponse
+	rlData, err := c.doRequest(ctx, body, &res)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return res.DomainList, rlData, nil
+}
+
+// GetDomainMembers returns every member of the domain, including domain admins.
+func (c *Client) GetDomainMembers(ctx context.Context, domain string) ([]DomainMember, *v2.RateLimitDescription, error) {
+	info, rlData, err := c.getDomainInfo(ctx, domain, "members")
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return info.Members, rlData, nil
+}
+
+// GetDomainGroups returns the groups of the domain.
+func (c *Client) GetDomainGroups(ctx context.Context, domain string) ([]DomainGroup, *v2.RateLimitDescription, error) {
+	info, rlData, err := c.getDomainInfo(ctx, domain, "groups")
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return info.Groups, rlData, nil
+}
+
+// GetDomainGroupMembers returns the members of a single domain group.
+func (c *Client) GetDomainGroupMembers(ctx context.Context, domain string, groupID string) ([]DomainMember, *v2.RateLimitDescription, error) {
+	body := DomainGroupRequestBody{
+		Type: "get",
+		Credentials: Credentials{
+			PartnerUserID:     c.partnerUserID,
+			PartnerUserSecret: c.partnerUserSecret,
+		},
+		InputSettings: DomainGroupInputSettings{
+			Type:    "domainGroup",
+			Domain:  domain,
+			GroupID: groupID,
+		},
+	}
+
+	var res DomainGroupResponse
+	rlData, err := c.doRequest(ctx, body, &res)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return res.Members, rlData, nil
+}
+
+func (c *Client) getDomainInfo(ctx context.Context, domain string, fields ...string) (*DomainInfo, *v2.RateLimitDescription, error) {
+	body := DomainRequestBody{
+		Type: "get",
+		Credentials: Credentials{
+			PartnerUserID:     c.partnerUserID,
+			PartnerUserSecret: c.partnerUserSecret,
+		},
+		InputSettings: DomainInputSettings{
+			Type:   "domain",
+			Domain: domain,
+			Fields: fields,
+		},
+	}
+
+	var res DomainResponse
+	rlData, err := c.doRequest(ctx, body, &res)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return &res.DomainInfo, rlData, nil
+}
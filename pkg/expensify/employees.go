@@ -0,0 +1,115 @@
+package expensify
+
+import (
+	"context"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+// EmployeeUpdate is a single row of the employee updater job. Only the set fields are changed.
+type EmployeeUpdate struct {
+	EmployeeEmail string `json:"employeeEmail"`
+	ManagerEmail  string `json:"managerEmail,omitempty"`
+	PolicyID      string `json:"policyID"`
+	Role          string `json:"role,omitempty"`
+	EmployeeID    string `json:"employeeID,omitempty"`
+	FirstName     string `json:"firstName,omitempty"`
+	LastName      string `json:"lastName,omitempty"`
+	// ApprovesTo is who the employee forwards the reports they approve to.
+	ApprovesTo string `json:"approvesTo,omitempty"`
+	// OverLimitApprover approves the reports above ApprovalLimit that the employee approves.
+	OverLimitApprover string `json:"overLimitApprover,omitempty"`
+	// ApprovalLimit is in cents of the policy output currency.
+	ApprovalLimit *int64 `json:"approvalLimit,omitempty"`
+	// IsTerminated removes the employee from PolicyID.
+	IsTerminated bool `json:"isTerminated,omitempty"`
+}
+
+type EmployeesData struct {
+	Employees []EmployeeUpdate `json:"Employees"`
+}
+
+type EmployeeUpdaterInputSettings struct {
+	Type   string `json:"type"`
+	Entity string `json:"entity"`
+}
+
+type EmployeeUpdaterRequestBody struct {
+	Type          string                       `json:"type"`
+	Credentials   Credentials                  `json:"credentials"`
+	InputSettings EmployeeUpdaterInputSettings `json:"inputSettings"`
+}
+
+func (b EmployeeUpdaterRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+type EmployeeUpdaterResponse struct {
+	ResponseCode int64 `json:"responseCode"`
+}
+
+// UpdateEmployees adds, updates or removes policy employees through the employee updater job.
+func (c *Client) UpdateEmployees(ctx context.Context, employees []EmployeeUpdate) (*v2.RateLimitDescription, error) {
+	body := EmployeeUpdaterRequestBody{
+		Type: "update",
+		Credentials: Credentials{
+			PartnerUserID:     c.partnerUserID,
+			PartnerUserSecret: c.partnerUserSecret,
+		},
+		InputSettings: EmployeeUpdaterInputSettings{
+			Type:   "employees",
+			Entity: "generic",
+		},
+	}
+
+	var res EmployeeUpdaterResponse
+	return c.doRequestWithData(ctx, body, EmployeesData{Employees: employees}, &res)
+}
+
+// SetEmployeeRole adds the employee to the policy with the given role, or changes their role if they
+// are already a member. managerEmail is who the employee submits reports to.
+func (c *Client) SetEmployeeRole(ctx context.Context, policyID, email, role, managerEmail string) (*v2.RateLimitDescription, error) {
+	return c.UpdateEmployees(ctx, []EmployeeUpdate{
+		{
+			EmployeeEmail: email,
+			ManagerEmail:  managerEmail,
+			PolicyID:      policyID,
+			Role:          role,
+		},
+	})
+}
+
+// RemoveEmployee removes the employee from the policy.
+func (c *Client) RemoveEmployee(ctx context.Context, policyID, email string) (*v2.RateLimitDescription, error) {
+	return c.UpdateEmployees(ctx, []EmployeeUpdate{
+		{
+			EmployeeEmail: email,
+			PolicyID:      policyID,
+			IsTerminated:  true,
+		},
+	})
+}
+
+// SetEmployeeApprover changes who the employee submits reports to.
+func (c *Client) SetEmployeeApprover(ctx context.Context, policyID, email, approverEmail string) (*v2.RateLimitDescription, error) {
+	return c.UpdateEmployees(ctx, []EmployeeUpdate{
+		{
+			EmployeeEmail: email,
+			ManagerEmail:  approverEmail,
+			PolicyID:      policyID,
+		},
+	})
+}
+
+// SetEmployeeApprovalLimit sets how much the employee can approve, in cents of the policy output
+// currency. Reports above the limit go to overLimitApprover.
+func (c *Client) SetEmployeeApprovalLimit(ctx context.Context, policyID, email string, limit int64, overLimitApprover string) (*v2.RateLimitDescription, error) {
+	return c.UpdateEmployees(ctx, []EmployeeUpdate{
+		{
+			EmployeeEmail:     email,
+			PolicyID:          policyID,
+			ApprovalLimit:     &limit,
+			OverLimitApprover: overLimitApprover,
+		},
+	})
+}
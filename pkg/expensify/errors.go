@@ -0,0 +1,71 @@
+package expensify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrAuthentication is returned when the partner credentials are missing, invalid or lack access.
+	ErrAuthentication = errors.New("expensify: authentication failed")
+	// ErrRateLimited is returned when the Integration Server throttles the request.
+	ErrRateLimited = errors.New("expensify: rate limited")
+	// ErrNotFound is returned when the requested policy, employee or other object does not exist.
+	ErrNotFound = errors.New("expensify: not found")
+	// ErrServer is returned for transient failures on the Integration Server side.
+	ErrServer = errors.New("expensify: transient server error")
+)
+
+// Error is a failed Integration Server job, as reported by the responseCode in the response body.
+type Error struct {
+	ResponseCode    int    `json:"responseCode"`
+	ResponseMessage string `json:"responseMessage"`
+	// JobType identifies the job that failed, e.g. "get/policyList".
+	JobType string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("expensify: %s job failed with response code %d: %s", e.JobType, e.ResponseCode, e.ResponseMessage)
+}
+
+// Is allows matching an Error against the sentinel errors with errors.Is.
+func (e *Error) Is(target error) bool {
+	switch e.ResponseCode {
+	// The Integration Server reports invalid partner credentials as 407 "Authentication error".
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusProxyAuthRequired:
+		return target == ErrAuthentication
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return target == ErrServer
+	default:
+		return false
+	}
+}
+
+// GRPCStatus maps the error to a gRPC status so the syncer retries transient faults and fails fast on auth.
+func (e *Error) GRPCStatus() *status.Status {
+	var code codes.Code
+	switch e.ResponseCode {
+	case http.StatusUnauthorized, http.StatusProxyAuthRequired:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Unknown
+	}
+
+	return status.New(code, e.Error())
+}
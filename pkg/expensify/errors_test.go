@@ -0,0 +1,67 @@
+package expensify
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorIs(t *testing.T) {
+	sentinels := []error{ErrAuthentication, ErrRateLimited, ErrNotFound, ErrServer}
+
+	tests := []struct {
+		responseCode int
+		want         error
+	}{
+		{401, ErrAuthentication},
+		{403, ErrAuthentication},
+		{407, ErrAuthentication},
+		{404, ErrNotFound},
+		{429, ErrRateLimited},
+		{500, ErrServer},
+		{502, ErrServer},
+		{503, ErrServer},
+		{504, ErrServer},
+		{400, nil},
+		{410, nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.responseCode), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &Error{ResponseCode: tt.responseCode, JobType: "get/policyList"})
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%d, %v) = %v, want %v", tt.responseCode, sentinel, got, !got)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorGRPCStatus(t *testing.T) {
+	tests := []struct {
+		responseCode int
+		want         codes.Code
+	}{
+		{401, codes.Unauthenticated},
+		{407, codes.Unauthenticated},
+		{403, codes.PermissionDenied},
+		{404, codes.NotFound},
+		{429, codes.Unavailable},
+		{500, codes.Unavailable},
+		{502, codes.Unavailable},
+		{503, codes.Unavailable},
+		{504, codes.DeadlineExceeded},
+		{400, codes.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.responseCode), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &Error{ResponseCode: tt.responseCode, JobType: "get/policyList"})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("status.Code(%d) = %v, want %v", tt.responseCode, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,29 @@
 package expensify
 
+import "strings"
+
+// User is an employee record from a policy's employees field.
 type User struct {
-	Role      string `json:"role"`
-	Email     string `json:"email"`
-	SubmitsTo string `json:"submitsTo"`
+	Role                string `json:"role"`
+	Email               string `json:"email"`
+	SubmitsTo           string `json:"submitsTo"`
+	ForwardsTo          string `json:"forwardsTo"`
+	OverLimitForwardsTo string `json:"overLimitForwardsTo"`
+	// ApprovalLimit is in cents of the policy output currency.
+	ApprovalLimit int64  `json:"approvalLimit"`
+	EmployeeID    string `json:"employeeID"`
+	FirstName     string `json:"firstName"`
+	LastName      string `json:"lastName"`
+	CustomField1  string `json:"customField1"`
+	CustomField2  string `json:"customField2"`
+	IsTerminated  bool   `json:"isTerminated"`
+	// Pending is set while the employee hasn't accepted their invitation yet.
+	Pending bool `json:"pending"`
+}
+
+// FullName returns the first and last name of the employee, or an empty string if neither is set.
+func (u *User) FullName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
 }
 
 type Policy struct {
@@ -0,0 +1,160 @@
+package expensify
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/sdk"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Option configures optional behaviour of the Client.
+type Option func(*Client)
+
+// WithMaxRetries sets how many times a throttled request is retried before giving up.
+func WithMaxRetries(maxRetries int) Option {
+	return func(c *Client) {
+		if maxRetries >= 0 {
+			c.maxRetries = maxRetries
+		}
+	}
+}
+
+// WithBaseURL points the client at a different Integration Server endpoint, e.g. a local stand-in.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = baseURL
+		}
+	}
+}
+
+// WithProxy sends all requests through the given HTTP(S) proxy instead of the one from the environment.
+func WithProxy(proxyURL string) Option {
+	return func(c *Client) {
+		c.proxyURL = proxyURL
+	}
+}
+
+// WithCABundle trusts the PEM encoded certificates in the file at path in addition to the system roots.
+func WithCABundle(path string) Option {
+	return func(c *Client) {
+		c.caBundlePath = path
+	}
+}
+
+// WithRequestTimeout limits how long a single request may take, including reading the response.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.requestTimeout = timeout
+		}
+	}
+}
+
+func (c *Client) newHTTPClient(ctx context.Context) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.caBundlePath != "" {
+		pool, err := loadCABundle(c.caBundlePath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	httpClient, err := uhttp.NewClient(ctx,
+		uhttp.WithLogger(true, ctxzap.Extract(ctx)),
+		uhttp.WithTLSClientConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The uhttp transport always honours the proxy environment variables, so an explicit proxy
+	// needs a transport of our own, wrapped to keep the SDK user agent and request logging.
+	if c.proxyURL != "" {
+		proxy, err := url.Parse(c.proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %q: %w", c.proxyURL, err)
+		}
+		transport, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("unexpected default transport type %T", http.DefaultTransport)
+		}
+		transport = transport.Clone()
+		transport.Proxy = http.ProxyURL(proxy)
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = &proxyTransport{next: transport}
+	}
+
+	if c.requestTimeout > 0 {
+		httpClient.Timeout = c.requestTimeout
+	}
+
+	return httpClient, nil
+}
+
+// proxyTransport sets the user agent and logs requests the way the uhttp transport does.
+type proxyTransport struct {
+	next http.RoundTripper
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "baton-sdk/"+sdk.Version)
+	}
+
+	fields := []zap.Field{
+		zap.String("http.method", req.Method),
+		zap.String("http.url_details.host", req.URL.Host),
+		zap.String("http.url_details.path", req.URL.Path),
+	}
+	l := ctxzap.Extract(req.Context())
+	l.Debug("Request started", fields...)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	if resp != nil {
+		fields = append(fields, zap.Int("http.status_code", resp.StatusCode))
+	}
+	l.Debug("Request complete", fields...)
+
+	return resp, err
+}
+
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in ca bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+// WithNonAdminPolicies lists the policies the user is only an auditor or employee of along with the
+// ones they administer.
+func WithNonAdminPolicies(include bool) Option {
+	return func(c *Client) {
+		c.adminOnly = !include
+	}
+}
@@ -0,0 +1,137 @@
+package expensify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+type PolicyCreatorInputSettings struct {
+	Type       string `json:"type"`
+	PolicyName string `json:"policyName"`
+	// Plan is "corporate" or "team", personal policies can't be created through the API.
+	Plan string `json:"plan,omitempty"`
+}
+
+type PolicyCreatorRequestBody struct {
+	Type          string                     `json:"type"`
+	Credentials   Credentials                `json:"credentials"`
+	InputSettings PolicyCreatorInputSettings `json:"inputSettings"`
+}
+
+func (b PolicyCreatorRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+type PolicyCreatorResponse struct {
+	ResponseCode int64  `json:"responseCode"`
+	PolicyID     string `json:"policyID"`
+	PolicyName   string `json:"policyName"`
+}
+
+type PolicyUpdaterInputSettings struct {
+	Type         string   `json:"type"`
+	PolicyIDList []string `json:"policyIDList"`
+}
+
+type PolicyUpdaterRequestBody struct {
+	Type          string                     `json:"type"`
+	Credentials   Credentials                `json:"credentials"`
+	InputSettings PolicyUpdaterInputSettings `json:"inputSettings"`
+}
+
+func (b PolicyUpdaterRequestBody) jobType() string {
+	return b.Type + "/" + b.InputSettings.Type
+}
+
+// PolicyUpdate is the payload of the policy updater job. Only the set fields are changed.
+type PolicyUpdate struct {
+	Owner          string `json:"owner,omitempty"`
+	OutputCurrency string `json:"outputCurrency,omitempty"`
+}
+
+type PolicyUpdaterResponse struct {
+	ResponseCode int64 `json:"responseCode"`
+}
+
+// UpdatePolicies applies the update to every policy in policyIDs through the policy updater job. The
+// job reports success even when it ignores a field, so the policies are read back to verify the update.
+func (c *Client) UpdatePolicies(ctx context.Context, policyIDs []string, update PolicyUpdate) (*v2.RateLimitDescription, error) {
+	body := PolicyUpdaterRequestBody{
+		Type: "update",
+		Credentials: Credentials{
+			PartnerUserID:     c.partnerUserID,
+			PartnerUserSecret: c.partnerUserSecret,
+		},
+		InputSettings: PolicyUpdaterInputSettings{
+			Type:         "policy",
+			PolicyIDList: policyIDs,
+		},
+	}
+
+	var res PolicyUpdaterResponse
+	rlData, err := c.doRequestWithData(ctx, body, update, &res)
+	if err != nil {
+		return rlData, err
+	}
+
+	policies, listRlData, err := c.GetPolicies(ctx)
+	if listRlData != nil {
+		rlData = listRlData
+	}
+	if err != nil {
+		return rlData, fmt.Errorf("expensify: failed to verify the policy update: %w", err)
+	}
+
+	for _, policyID := range policyIDs {
+		i := slices.IndexFunc(policies, func(policy Policy) bool { return policy.ID == policyID })
+		switch {
+		case i < 0:
+			return rlData, fmt.Errorf("expensify: failed to verify the policy update, policy %s is no longer listed", policyID)
+		case update.Owner != "" && !strings.EqualFold(policies[i].Owner, update.Owner):
+			return rlData, fmt.Errorf("expensify: policy %s is still owned by %s after the update", policyID, policies[i].Owner)
+		case update.OutputCurrency != "" && !strings.EqualFold(policies[i].OutputCurrency, update.OutputCurrency):
+			return rlData, fmt.Errorf("expensify: policy %s still has output currency %s after the update", policyID, policies[i].OutputCurrency)
+		}
+	}
+
+	return rlData, nil
+}
+
+// TransferPolicyOwnership makes newOwner the owner of the policy. The new owner must already be an
+// admin of the policy.
+func (c *Client) TransferPolicyOwnership(ctx context.Context, policyID, newOwner string) (*v2.RateLimitDescription, error) {
+	return c.UpdatePolicies(ctx, []string{policyID}, PolicyUpdate{Owner: newOwner})
+}
+
+// CreatePolicy creates a policy on the given plan through the policy creator job. The credentials'
+// account becomes its owner.
+func (c *Client) CreatePolicy(ctx context.Context, name, plan string) (*Policy, *v2.RateLimitDescription, error) {
+	body := PolicyCreatorRequestBody{
+		Type: "create",
+		Credentials: Credentials{
+			PartnerUserID:     c.partnerUserID,
+			PartnerUserSecret: c.partnerUserSecret,
+		},
+		InputSettings: PolicyCreatorInputSettings{
+			Type:       "policy",
+			PolicyName: name,
+			Plan:       plan,
+		},
+	}
+
+	var res PolicyCreatorResponse
+	rlData, err := c.doRequest(ctx, body, &res)
+	if err != nil {
+		return nil, rlData, err
+	}
+
+	return &Policy{
+		ID:   res.PolicyID,
+		Name: res.PolicyName,
+		Type: plan,
+	}, rlData, nil
+}
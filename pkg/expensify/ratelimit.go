@@ -0,0 +1,78 @@
+package expensify
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+const (
+	// DefaultMaxRetries is the number of retries made for a throttled request when no budget is configured.
+	DefaultMaxRetries = 5
+
+	baseBackoff = 2 * time.Second
+	maxBackoff  = 60 * time.Second
+)
+
+// backoff returns how long to wait before retrying a throttled request. A reset time reported by the
+// server wins when it is usable, otherwise the wait grows exponentially. Jitter is always added so
+// concurrent syncs don't retry in lockstep.
+func backoff(attempt int, rlData *v2.RateLimitDescription) time.Duration {
+	if rlData != nil && rlData.GetResetAt() != nil {
+		if wait := time.Until(rlData.GetResetAt().AsTime()); wait > 0 && wait <= maxBackoff {
+			return wait + jitter(baseBackoff)
+		}
+	}
+
+	wait := maxBackoff
+	if attempt < 5 {
+		wait = min(baseBackoff<<attempt, maxBackoff)
+	}
+
+	return wait/2 + jitter(wait/2)
+}
+
+func jitter(upTo time.Duration) time.Duration {
+	if upTo <= 0 {
+		return 0
+	}
+	return rand.N(upTo) //nolint:gosec // jitter does not need a cryptographically secure source.
+}
+
+// throttle holds back every request until the latest backoff has passed, so requests made concurrently
+// share one rate limit budget instead of each hammering the server on its own schedule.
+type throttle struct {
+	mtx       sync.Mutex
+	resumesAt time.Time
+}
+
+// until holds requests back until t, unless they are already held back for longer.
+func (t *throttle) until(resumesAt time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if resumesAt.After(t.resumesAt) {
+		t.resumesAt = resumesAt
+	}
+}
+
+// wait blocks until requests may be sent again or the context is done.
+func (t *throttle) wait(ctx context.Context) error {
+	t.mtx.Lock()
+	wait := time.Until(t.resumesAt)
+	t.mtx.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package expensify
+
+import (
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		resetIn time.Duration
+		min     time.Duration
+		max     time.Duration
+	}{
+		{"first attempt", 0, 0, time.Second, 2 * time.Second},
+		{"second attempt", 1, 0, 2 * time.Second, 4 * time.Second},
+		{"fifth attempt", 4, 0, 16 * time.Second, 32 * time.Second},
+		{"capped", 5, 0, 30 * time.Second, 60 * time.Second},
+		{"past the cap", 10, 0, 30 * time.Second, 60 * time.Second},
+		{"reset time wins", 0, 20 * time.Second, 19 * time.Second, 22 * time.Second},
+		{"reset time in the past is ignored", 1, -time.Minute, 2 * time.Second, 4 * time.Second},
+		{"reset time too far out is ignored", 1, 2 * time.Hour, 2 * time.Second, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rlData *v2.RateLimitDescription
+			if tt.resetIn != 0 {
+				rlData = &v2.RateLimitDescription{ResetAt: timestamppb.New(time.Now().Add(tt.resetIn))}
+			}
+
+			for range 20 {
+				if got := backoff(tt.attempt, rlData); got < tt.min || got > tt.max {
+					t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}